@@ -0,0 +1,112 @@
+package duckgo
+
+import (
+	"aurora/internal/transcript"
+	anthropictypes "aurora/typings/anthropic"
+	duckgotypes "aurora/typings/duckgo"
+	officialtypes "aurora/typings/official"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// ConvertMessagesRequest 将 Anthropic Messages 请求转换为 DuckDuckGo 格式。
+// 请求先被映射为等价的 OpenAI 消息列表，再复用 ConvertAPIRequest 的转换逻辑，
+// 这样两种协议的消息处理规则保持一致。
+func ConvertMessagesRequest(request anthropictypes.MessagesRequest, tmpl *transcript.Template) (duckgotypes.ApiRequest, error) {
+	apiRequest := officialtypes.APIRequest{
+		Model:  request.Model,
+		Stream: request.Stream,
+	}
+	if system := anthropicContentToParts(request.System); system != nil {
+		apiRequest.AddMessage("system", system)
+	}
+	for _, msg := range request.Messages {
+		if content := anthropicContentToParts(msg.Content); content != nil {
+			apiRequest.AddMessage(msg.Role, content)
+		}
+	}
+
+	duckgoRequest, err := ConvertAPIRequest(apiRequest, tmpl)
+	if err != nil {
+		return duckgotypes.ApiRequest{}, err
+	}
+	duckgoRequest.StopSequences = request.StopSequences
+	duckgoRequest.MaxTokens = request.MaxTokens
+	return duckgoRequest, nil
+}
+
+// anthropicContentToParts 将 Anthropic 的 content（字符串或内容块数组）
+// 转换为 OpenAI 格式的 content。无法识别的内容块会被忽略。
+func anthropicContentToParts(content any) any {
+	switch v := content.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return nil
+		}
+		return v
+	}
+
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil
+	}
+	var blocks []anthropictypes.ContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil
+	}
+
+	var parts []any
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			parts = append(parts, map[string]any{"type": "text", "text": block.Text})
+		case "image":
+			if url := anthropicImageURL(block.Source); url != "" {
+				parts = append(parts, map[string]any{
+					"type":      "image_url",
+					"image_url": map[string]any{"url": url},
+				})
+			}
+		case "document":
+			if data := anthropicDocumentData(block.Source); data != "" {
+				parts = append(parts, map[string]any{
+					"type": "file",
+					"file": map[string]any{"filename": block.Title, "file_data": data},
+				})
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return parts
+}
+
+func anthropicImageURL(source *anthropictypes.ImageSource) string {
+	if source == nil {
+		return ""
+	}
+	switch source.Type {
+	case "base64":
+		return "data:" + source.MediaType + ";base64," + source.Data
+	case "url":
+		return source.URL
+	}
+	return ""
+}
+
+// anthropicDocumentData 把 document 内容块的来源转换为 data URL，不支持远程 URL。
+func anthropicDocumentData(source *anthropictypes.ImageSource) string {
+	if source == nil {
+		return ""
+	}
+	switch source.Type {
+	case "base64":
+		return "data:" + source.MediaType + ";base64," + source.Data
+	case "text":
+		return "data:text/plain;base64," + base64.StdEncoding.EncodeToString([]byte(source.Data))
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package duckgo
+
+import (
+	"aurora/internal/transcript"
+	duckgotypes "aurora/typings/duckgo"
+	officialtypes "aurora/typings/official"
+)
+
+// ConvertCompletionRequest 将旧版文本补全请求中的单个 prompt 包装为一条用户消息。
+// 设置了 suffix 时，会改为让模型补全 prompt 与 suffix 之间的内容。
+// 转换失败（例如模板渲染出错）时返回 ConvertAPIRequest 的错误。
+func ConvertCompletionRequest(request officialtypes.CompletionRequest, prompt string, tmpl *transcript.Template) (duckgotypes.ApiRequest, error) {
+	apiRequest := officialtypes.APIRequest{
+		Model:  request.Model,
+		Stream: request.Stream,
+	}
+	if request.Suffix != "" {
+		prompt = "Write the text that belongs between the prefix and the suffix below. " +
+			"Reply with the missing text only, without repeating the prefix or the suffix.\n\n" +
+			"<prefix>\n" + prompt + "\n</prefix>\n<suffix>\n" + request.Suffix + "\n</suffix>"
+	}
+	apiRequest.AddMessage("user", prompt)
+
+	duckgoRequest, err := ConvertAPIRequest(apiRequest, tmpl)
+	if err != nil {
+		return duckgotypes.ApiRequest{}, err
+	}
+	duckgoRequest.StopSequences = stopSequences(request.Stop)
+	duckgoRequest.MaxTokens = request.MaxTokens
+	return duckgoRequest, nil
+}
+
+// stopSequences 解析 OpenAI 的 stop 参数，它可以是字符串或字符串数组。
+func stopSequences(stop any) []string {
+	switch v := stop.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []any:
+		var sequences []string
+		for _, element := range v {
+			if s, ok := element.(string); ok && s != "" {
+				sequences = append(sequences, s)
+			}
+		}
+		return sequences
+	}
+	return nil
+}
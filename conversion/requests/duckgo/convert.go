@@ -1,6 +1,7 @@
 package duckgo
 
 import (
+	"aurora/internal/transcript"
 	duckgotypes "aurora/typings/duckgo"
 	officialtypes "aurora/typings/official"
 	"crypto/rand"
@@ -9,44 +10,109 @@ import (
 	"fmt"
 	"math/big"
 	"regexp"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
-func ConvertAPIRequest(apiRequest officialtypes.APIRequest) duckgotypes.ApiRequest {
+// ConvertAPIRequest 将 OpenAI 格式的请求转换为 DuckDuckGo 格式，系统前言和函数调用按 tmpl 渲染，tmpl 为 nil 时使用内置模板。
+// 消息内容的类型或结构不合法时返回 *ValidationError，指出出错的消息下标和字段。
+func ConvertAPIRequest(apiRequest officialtypes.APIRequest, tmpl *transcript.Template) (duckgotypes.ApiRequest, error) {
 	duckgoRequest := duckgotypes.NewApiRequest(apiRequest.Model)
 	duckgoRequest.Model = apiRequest.Model
-	buildMessage(&apiRequest, &duckgoRequest)
-	return duckgoRequest
+	if tmpl == nil {
+		tmpl = transcript.Default()
+	}
+	if err := buildMessage(&apiRequest, &duckgoRequest, tmpl); err != nil {
+		return duckgotypes.ApiRequest{}, err
+	}
+	// 生成密钥较慢，只为通过校验的请求生成
+	duckgoRequest.DurableStream = newDurableStream()
+	duckgoRequest.StopSequences = stopSequences(apiRequest.Stop)
+	duckgoRequest.MaxTokens = apiRequest.TokenLimit()
+	return duckgoRequest, nil
 }
 
-func buildMessage(apiRequest *officialtypes.APIRequest, duckgoRequest *duckgotypes.ApiRequest) {
+func buildMessage(apiRequest *officialtypes.APIRequest, duckgoRequest *duckgotypes.ApiRequest, tmpl *transcript.Template) error {
 	duckgoRequest.CanUseTools = true
 	duckgoRequest.CanUseApproxLocation = nil
-	duckgoRequest.ReasoningEffort = "none"
-	duckgoRequest.DurableStream = newDurableStream()
+	// 未指定时留空，由模型注册表填充默认值
+	duckgoRequest.ReasoningEffort = apiRequest.ReasoningEffort
 	// if strings.HasPrefix(duckgoRequest.Model, "claude") {
 	// 	duckgoRequest.ReasoningEffort = "none"
 	// }
-	duckgoRequest.Metadata.ToolChoice = duckgotypes.Tool{
-		LocalSearch:     false,
-		NewsSearch:      false,
-		VideosSearch:    false,
-		WeatherForecast: false,
+	applySearchTools(apiRequest, duckgoRequest)
+	if apiRequest.ToolsEnabled() {
+		duckgoRequest.AddMessageUser(buildToolPrompt(apiRequest, tmpl))
+	}
+	if apiRequest.JSONMode() {
+		duckgoRequest.AddMessageUser(buildResponseFormatPrompt(apiRequest))
 	}
-	for _, msg := range apiRequest.Messages {
+	// 注入的提示和系统前言在裁剪上下文时固定保留
+	duckgoRequest.PinnedMessages = len(duckgoRequest.Messages)
+	var system []string
+	// toolNames 记录助手发起的调用 ID 对应的函数名，用于渲染 tool 消息
+	toolNames := map[string]string{}
+	// 连续的 tool / function 消息合并为一条用户消息
+	var toolResults []string
+	flushToolResults := func() {
+		if len(toolResults) > 0 {
+			duckgoRequest.AddMessageUser(strings.Join(toolResults, "\n\n"))
+			toolResults = nil
+		}
+	}
+	for index, msg := range apiRequest.Messages {
 		if !isValidRole(msg.Role) {
-			continue
+			return invalidField(index, "role", "unsupported role %q", msg.Role)
 		}
 
-		role := normalizeRole(msg.Role)
-		switch role {
+		switch msg.Role {
+		case "system", "developer":
+			// 不论出现在对话的什么位置，都收集到前言中，不与对话内容混在一起
+			text, err := textContent(index, msg.Content)
+			if err != nil {
+				return err
+			}
+			if text != "" {
+				system = append(system, text)
+			}
+		case "tool", "function":
+			text, err := textContent(index, msg.Content)
+			if err != nil {
+				return err
+			}
+			name := msg.Name
+			if name == "" {
+				name = toolNames[msg.ToolCallID]
+			}
+			toolResults = append(toolResults, tmpl.ToolResult(msg.ToolCallID, name, text))
 		case "user":
-			handleUserMessage(msg.Content, duckgoRequest)
+			flushToolResults()
+			if err := handleUserMessage(index, msg.Content, duckgoRequest); err != nil {
+				return err
+			}
 		case "assistant":
-			handleAssistantMessage(msg.Content, duckgoRequest)
+			flushToolResults()
+			toolCalls := msg.ToolCalls
+			if msg.FunctionCall != nil {
+				toolCalls = append(toolCalls, officialtypes.ToolCall{Type: "function", Function: *msg.FunctionCall})
+			}
+			for _, call := range toolCalls {
+				toolNames[call.ID] = call.Function.Name
+			}
+			if err := handleAssistantMessage(index, msg.Content, toolCalls, tmpl, duckgoRequest); err != nil {
+				return err
+			}
 		}
 	}
+	flushToolResults()
+	mergeMessages(duckgoRequest, tmpl)
+	addPrefill(duckgoRequest, tmpl)
+	if len(system) > 0 {
+		duckgoRequest.PrependMessageUser(tmpl.System(system))
+		duckgoRequest.PinnedMessages++
+	}
+	return nil
 }
 
 func newDurableStream() *duckgotypes.DurableStream {
@@ -76,69 +142,155 @@ func isValidRole(role string) bool {
 		"user":      true,
 		"system":    true,
 		"assistant": true,
+		"tool":      true,
+		"function":  true,
 	}
 	return validRoles[role]
 }
 
-func normalizeRole(role string) string {
-	if role == "system" || role == "developer" {
-		return "user"
+// textContent 提取 system / developer / tool 消息中的文本，内容为数组时只取 text 部分。
+func textContent(index int, content any) (string, error) {
+	switch v := content.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(v), nil
+	case []any:
+		var texts []string
+		for j, element := range v {
+			part, ok := element.(map[string]any)
+			if !ok {
+				return "", invalidField(index, partField(j, ""), "expected an object")
+			}
+			if part["type"] != "text" {
+				continue
+			}
+			text, ok := part["text"].(string)
+			if !ok {
+				return "", invalidField(index, partField(j, "text"), "expected a string")
+			}
+			if strings.TrimSpace(text) != "" {
+				texts = append(texts, strings.TrimSpace(text))
+			}
+		}
+		return strings.Join(texts, "\n"), nil
 	}
-	return role
+	return "", invalidField(index, "content", "expected a string or an array of content parts")
 }
 
-func handleUserMessage(content any, duckgoRequest *duckgotypes.ApiRequest) {
-	if arrayContent, ok := content.([]any); ok {
-		parts := buildMessageParts(arrayContent)
+func handleUserMessage(index int, content any, duckgoRequest *duckgotypes.ApiRequest) error {
+	switch v := content.(type) {
+	case string:
+		duckgoRequest.AddMessageUser(v)
+	case []any:
+		if len(v) == 0 {
+			return invalidField(index, "content", "expected at least one content part")
+		}
+		parts, err := buildMessageParts(index, v)
+		if err != nil {
+			return err
+		}
 		duckgoRequest.AddMessageUser(parts)
-	} else {
-		duckgoRequest.AddMessageUser(content)
+	case nil:
+		return invalidField(index, "content", "user messages must have content")
+	default:
+		return invalidField(index, "content", "expected a string or an array of content parts")
 	}
+	return nil
 }
 
-func handleAssistantMessage(content any, duckgoRequest *duckgotypes.ApiRequest) {
-	parts := []any{}
-	if arrayContent, ok := content.([]any); ok {
-		parts = buildMessageParts(arrayContent)
-	} else {
-		parts = append(parts, duckgotypes.PartText{
-			Type: "text",
-			Text: content.(string),
-		})
-	}
-	duckgoRequest.AddMessageAssistant(parts)
+// handleAssistantMessage 转换一条助手消息。只有函数调用时 content 为 null，调用会被渲染为文本。
+func handleAssistantMessage(index int, content any, toolCalls []officialtypes.ToolCall, tmpl *transcript.Template, duckgoRequest *duckgotypes.ApiRequest) error {
+	if len(toolCalls) > 0 {
+		text, err := textContent(index, content)
+		if err != nil {
+			return err
+		}
+		text = formatAssistantToolCalls(text, toolCalls, tmpl)
+		duckgoRequest.AddMessageAssistant([]any{duckgotypes.PartText{Type: "text", Text: text}})
+		return nil
+	}
+	switch v := content.(type) {
+	case nil:
+		// 既没有内容也没有函数调用的助手消息没有意义，直接忽略
+	case string:
+		duckgoRequest.AddMessageAssistant([]any{duckgotypes.PartText{Type: "text", Text: v}})
+	case []any:
+		parts, err := buildMessageParts(index, v)
+		if err != nil {
+			return err
+		}
+		duckgoRequest.AddMessageAssistant(parts)
+	default:
+		return invalidField(index, "content", "expected a string or an array of content parts")
+	}
+	return nil
 }
 
-func buildMessageParts(content []any) []any {
+func buildMessageParts(index int, content []any) ([]any, error) {
 	var parts []any
-	for _, element := range content {
-		if elementMap, ok := element.(map[string]any); ok {
-			part := createPart(elementMap)
-			if part != nil {
-				parts = append(parts, part)
-			}
+	for j, element := range content {
+		elementMap, ok := element.(map[string]any)
+		if !ok {
+			return nil, invalidField(index, partField(j, ""), "expected an object")
 		}
+		part, err := createPart(elementMap)
+		if err != nil {
+			err.Index, err.Field = index, partField(j, err.Field)
+			return nil, err
+		}
+		parts = append(parts, part)
 	}
-	return parts
+	return parts, nil
 }
 
-func createPart(elementMap map[string]any) any {
+// createPart 转换一段内容。返回的错误中 Field 是相对于这段内容的路径，由调用方补全。
+func createPart(elementMap map[string]any) (any, *ValidationError) {
 	switch elementMap["type"] {
-	case "text":
-		return duckgotypes.PartText{
-			Type: "text",
-			Text: elementMap["text"].(string),
+	case "text", "refusal":
+		key, _ := elementMap["type"].(string)
+		text, ok := elementMap[key].(string)
+		if !ok {
+			return nil, &ValidationError{Field: key, Message: "expected a string"}
 		}
+		return duckgotypes.PartText{Type: "text", Text: text}, nil
 	case "image_url":
-		dataURL := elementMap["image_url"].(map[string]any)["url"].(string)
-		mime, _ := GetMimeType(dataURL)
+		// image_url 可以是 {"url": ...}，部分客户端直接传字符串
+		var url string
+		switch imageURL := elementMap["image_url"].(type) {
+		case map[string]any:
+			url, _ = imageURL["url"].(string)
+		case string:
+			url = imageURL
+		}
+		if url == "" {
+			return nil, &ValidationError{Field: "image_url.url", Message: "expected a non-empty string"}
+		}
+		mime, _ := GetMimeType(url)
 		return duckgotypes.PartImage{
 			Type:     "image",
 			MimeType: mime,
-			Image:    dataURL,
+			Image:    url,
+		}, nil
+	case "file":
+		// {"type": "file", "file": {"filename": ..., "file_data": "data:...;base64,..."}}
+		file, ok := elementMap["file"].(map[string]any)
+		if !ok {
+			return nil, &ValidationError{Field: "file", Message: "expected an object"}
+		}
+		data, _ := file["file_data"].(string)
+		if data == "" {
+			if _, hasID := file["file_id"]; hasID {
+				return nil, &ValidationError{Field: "file.file_id", Message: "uploaded files are not supported, send the content inline as file_data"}
+			}
+			return nil, &ValidationError{Field: "file.file_data", Message: "expected a non-empty string"}
 		}
+		filename, _ := file["filename"].(string)
+		return duckgotypes.PartFile{Type: "file", Filename: filename, Data: data}, nil
+	case nil:
+		return nil, &ValidationError{Field: "type", Message: "missing content part type"}
 	default:
-		return nil
+		return nil, &ValidationError{Field: "type", Message: fmt.Sprintf("unsupported content part type %q", fmt.Sprint(elementMap["type"]))}
 	}
 }
 
@@ -150,3 +302,36 @@ func GetMimeType(dataURL string) (string, error) {
 	}
 	return "", fmt.Errorf("无法提取 MIME 类型")
 }
+
+// CloneRequest 复制请求并生成新的 DurableStream，用于向上游发起一次独立的会话，
+// 例如 n > 1 时为每个 choice 各请求一次。
+func CloneRequest(request duckgotypes.ApiRequest) duckgotypes.ApiRequest {
+	clone := request
+	clone.Messages = append([]any{}, request.Messages...)
+	clone.DurableStream = newDurableStream()
+	return clone
+}
+
+// NewFollowUpRequest 复制原请求，在末尾追加模型的上一轮回复和一条新的用户指令，
+// 用于在模型输出不符合要求时让上游重新作答。
+func NewFollowUpRequest(request duckgotypes.ApiRequest, previous string, instruction string) duckgotypes.ApiRequest {
+	followUp := CloneRequest(request)
+	followUp.AddMessageAssistant([]any{duckgotypes.PartText{Type: "text", Text: previous}})
+	followUp.AddMessageUser(instruction)
+	return followUp
+}
+
+// NewSummaryRequest 使用原请求的模型和参数生成一个独立的会话，让上游总结较早的对话记录，
+// 用于上下文超出预算时的 summarize 策略。
+func NewSummaryRequest(request duckgotypes.ApiRequest, transcript string) duckgotypes.ApiRequest {
+	summary := CloneRequest(request)
+	summary.Messages = nil
+	summary.PinnedMessages = 0
+	summary.StopSequences = nil
+	summary.MaxTokens = 0
+	summary.Metadata.ToolChoice = duckgotypes.Tool{}
+	summary.AddMessageUser("Summarize the following earlier part of a conversation between a user and an assistant. " +
+		"Keep every fact, decision, name, number and open question that later messages may rely on. " +
+		"Reply with the summary only.\n\n" + transcript)
+	return summary
+}
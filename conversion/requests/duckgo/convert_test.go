@@ -0,0 +1,219 @@
+package duckgo
+
+import (
+	"aurora/internal/transcript"
+	anthropictypes "aurora/typings/anthropic"
+	duckgotypes "aurora/typings/duckgo"
+	geminitypes "aurora/typings/gemini"
+	officialtypes "aurora/typings/official"
+	ollamatypes "aurora/typings/ollama"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// conversionCorpus 覆盖各种合法和畸形的消息结构，用作变异测试和模糊测试的种子。
+var conversionCorpus = []string{
+	`{"model":"m","messages":[{"role":"user","content":"hi"}]}`,
+	`{"model":"m","messages":[{"role":"system","content":"sys"},{"role":"developer","content":[{"type":"text","text":"dev"}]},{"role":"user","content":[{"type":"text","text":"look"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]}]}`,
+	`{"model":"m","messages":[{"role":"user","content":[{"type":"image_url","image_url":"https://example.com/a.png"},{"type":"file","file":{"filename":"a.txt","file_data":"data:text/plain;base64,aGk="}}]}]}`,
+	`{"model":"m","tools":[{"type":"function","function":{"name":"f","parameters":{"type":"object"}}}],"messages":[{"role":"user","content":"q"},{"role":"assistant","content":null,"tool_calls":[{"id":"c1","type":"function","function":{"name":"f","arguments":"{}"}}]},{"role":"tool","tool_call_id":"c1","content":"ok"},{"role":"assistant","content":[{"type":"text","text":"done"},{"type":"refusal","refusal":"no"}]}]}`,
+	`{"model":"m","messages":[{"role":"assistant","content":"","function_call":{"name":"f","arguments":"{"}},{"role":"function","name":"f","content":[{"type":"text","text":"r"}]}]}`,
+	`{"model":"m","response_format":{"type":"json_schema","json_schema":{"name":"x","schema":{"type":"object"}}},"stop":["a",1,null],"messages":[{"role":"user","content":"x"}]}`,
+	`{"model":"m","messages":[{"role":"user","content":[{"type":"file","file":{"file_id":"file-1"}}]}]}`,
+	`{"model":"m","messages":[{"role":"user","content":[null,1,"x",{"type":null},{"type":"text","text":{}}]}]}`,
+	`{"model":"m","messages":[{"role":"user","content":{"text":"object"}},{"role":"critic","content":"x"}]}`,
+}
+
+// safeBuild 转换消息，把 panic 转为测试失败。
+func safeBuild(t *testing.T, body string) (err error) {
+	t.Helper()
+	var request officialtypes.APIRequest
+	if json.Unmarshal([]byte(body), &request) != nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("conversion panicked on %s: %v", body, r)
+		}
+	}()
+	err = buildMessage(&request, &duckgotypes.ApiRequest{}, transcript.Default())
+	var validationErr *ValidationError
+	if err != nil && !errors.As(err, &validationErr) {
+		t.Fatalf("expected *ValidationError for %s, got %T: %v", body, err, err)
+	}
+	return err
+}
+
+func TestConvertAPIRequestValidation(t *testing.T) {
+	cases := []struct {
+		body  string
+		param string
+	}{
+		{`{"messages":[{"role":"user","content":"hi"},{"role":"user","content":[{"type":"text","text":5}]}]}`, "messages[1].content[0].text"},
+		{`{"messages":[{"role":"user","content":[{"type":"text","text":"a"},{"type":"image_url","image_url":{"url":7}}]}]}`, "messages[0].content[1].image_url.url"},
+		{`{"messages":[{"role":"assistant","content":42}]}`, "messages[0].content"},
+		{`{"messages":[{"role":"user","content":null}]}`, "messages[0].content"},
+		{`{"messages":[{"role":"user","content":["text"]}]}`, "messages[0].content[0]"},
+		{`{"messages":[{"role":"user","content":[{"type":"input_audio"}]}]}`, "messages[0].content[0].type"},
+		{`{"messages":[{"role":"user","content":[{"type":"file","file":{"file_id":"file-1"}}]}]}`, "messages[0].content[0].file.file_id"},
+		{`{"messages":[{"role":"system","content":"s"},{"role":"critic","content":"x"}]}`, "messages[1].role"},
+	}
+	for _, tc := range cases {
+		err := safeBuild(t, tc.body)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Param() != tc.param {
+			t.Errorf("%s: expected error at %s, got %v", tc.body, tc.param, err)
+		}
+	}
+
+	// 合法请求完整转换，包括生成 DurableStream
+	var request officialtypes.APIRequest
+	json.Unmarshal([]byte(conversionCorpus[3]), &request)
+	if converted, err := ConvertAPIRequest(request, nil); err != nil || converted.DurableStream == nil {
+		t.Fatalf("valid request failed to convert: %v", err)
+	}
+}
+
+func TestConvertWithTemplate(t *testing.T) {
+	var request officialtypes.APIRequest
+	json.Unmarshal([]byte(`{"model":"m","messages":[
+		{"role":"system","content":"Be brief."},
+		{"role":"user","content":"first"},
+		{"role":"user","content":"second"},
+		{"role":"developer","content":"Answer in French."},
+		{"role":"assistant","content":"Bonjour,"}]}`), &request)
+
+	// 内置模板不合并消息、不追加续写指令，系统前言插入开头并固定保留
+	converted := duckgotypes.ApiRequest{}
+	if err := buildMessage(&request, &converted, transcript.Default()); err != nil {
+		t.Fatal(err)
+	}
+	preamble := converted.Messages[0].(duckgotypes.MessageUser).Content.(string)
+	if len(converted.Messages) != 4 || converted.PinnedMessages != 1 ||
+		!strings.HasPrefix(preamble, "<system_instructions>\nBe brief.\n\nAnswer in French.\n</system_instructions>") {
+		t.Fatalf("unexpected messages %+v", converted.Messages)
+	}
+
+	tmpl, err := transcript.Parse("alternating", `
+{{- define "system"}}Rules:{{range .Messages}} {{.}}{{end}}{{end}}
+{{- define "merge"}}{{range $i, $m := .Messages}}{{if $i}}
+---
+{{end}}{{$m}}{{end}}{{end}}
+{{- define "prefill"}}Continue from: {{.Text}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted = duckgotypes.ApiRequest{}
+	if err := buildMessage(&request, &converted, tmpl); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Rules: Be brief. Answer in French.", "first\n---\nsecond", "", "Continue from: Bonjour,"}
+	if len(converted.Messages) != len(want) {
+		t.Fatalf("unexpected messages %+v", converted.Messages)
+	}
+	for i, text := range want {
+		if user, ok := converted.Messages[i].(duckgotypes.MessageUser); ok && user.Content != text {
+			t.Errorf("message %d = %q, want %q", i, user.Content, text)
+		}
+	}
+}
+
+// TestConversionNeverPanics 把语料中每个 JSON 值依次替换为各种类型的值，确认转换只返回错误而不会 panic。
+func TestConversionNeverPanics(t *testing.T) {
+	replacements := []any{nil, 1.5, "x", true, []any{}, []any{1.0}, map[string]any{}, map[string]any{"type": "text"}}
+	cases := 0
+	for _, seed := range conversionCorpus {
+		var root any
+		if err := json.Unmarshal([]byte(seed), &root); err != nil {
+			t.Fatalf("invalid seed %s: %v", seed, err)
+		}
+		for _, path := range valuePaths(root, nil) {
+			for _, replacement := range replacements {
+				mutated, _ := json.Marshal(replaceAt(root, path, replacement))
+				safeBuild(t, string(mutated))
+				cases++
+			}
+		}
+	}
+	t.Logf("checked %d mutated requests", cases)
+}
+
+// valuePaths 返回 JSON 值中所有节点的路径，路径元素为对象的键或数组下标。
+func valuePaths(value any, prefix []any) [][]any {
+	paths := [][]any{append([]any(nil), prefix...)}
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			paths = append(paths, valuePaths(child, append(prefix, key))...)
+		}
+	case []any:
+		for i, child := range v {
+			paths = append(paths, valuePaths(child, append(prefix, i))...)
+		}
+	}
+	return paths
+}
+
+// replaceAt 返回把 path 处的值替换为 replacement 后的副本。
+func replaceAt(value any, path []any, replacement any) any {
+	if len(path) == 0 {
+		return replacement
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		clone := make(map[string]any, len(v))
+		for key, child := range v {
+			clone[key] = child
+		}
+		key := path[0].(string)
+		clone[key] = replaceAt(v[key], path[1:], replacement)
+		return clone
+	case []any:
+		clone := append([]any(nil), v...)
+		i := path[0].(int)
+		clone[i] = replaceAt(v[i], path[1:], replacement)
+		return clone
+	}
+	panic(fmt.Sprintf("invalid path %v", path))
+}
+
+// FuzzConvertRequests 以语料为种子，用同一份输入驱动所有协议的转换入口，`go test` 只运行种子。
+func FuzzConvertRequests(f *testing.F) {
+	for _, seed := range conversionCorpus {
+		f.Add(seed)
+	}
+	f.Add(`{"model":"m","system":[{"type":"text","text":"s"}],"messages":[{"role":"user","content":[{"type":"document","source":{"type":"text","data":"doc"}}]}]}`)
+	f.Add(`{"contents":[{"role":"user","parts":[{"text":"hi"},{"inlineData":{"mimeType":"application/pdf","data":"JVBERi0="}}]}],"systemInstruction":{"parts":[{"text":"s"}]}}`)
+	f.Add(`{"model":"m","messages":[{"role":"user","content":"hi","images":["iVBORw0KGgo="]}],"options":{"stop":["x"],"num_predict":-1}}`)
+	f.Fuzz(func(t *testing.T, body string) {
+		data := []byte(body)
+		safeBuild(t, body)
+
+		var anthropic anthropictypes.MessagesRequest
+		if json.Unmarshal(data, &anthropic) == nil {
+			anthropicContentToParts(anthropic.System)
+			for _, msg := range anthropic.Messages {
+				anthropicContentToParts(msg.Content)
+			}
+		}
+		var gemini geminitypes.GenerateContentRequest
+		if json.Unmarshal(data, &gemini) == nil {
+			for _, content := range gemini.Contents {
+				geminiPartsToParts(content.Parts)
+			}
+		}
+		var ollama ollamatypes.ChatRequest
+		if json.Unmarshal(data, &ollama) == nil {
+			for _, msg := range ollama.Messages {
+				ollamaContent(msg.Content, msg.Images)
+			}
+		}
+		var responses officialtypes.ResponsesRequest
+		if json.Unmarshal(data, &responses) == nil {
+			ResponsesToAPIRequest(responses, nil)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package duckgo
+
+import (
+	"aurora/internal/transcript"
+	duckgotypes "aurora/typings/duckgo"
+	geminitypes "aurora/typings/gemini"
+	officialtypes "aurora/typings/official"
+	"strings"
+)
+
+// ConvertGenerateContentRequest 将 Gemini generateContent 请求转换为 DuckDuckGo 格式。
+// 与 Anthropic 请求一样，先映射为 OpenAI 消息列表再复用 ConvertAPIRequest。
+func ConvertGenerateContentRequest(model string, request geminitypes.GenerateContentRequest, stream bool, tmpl *transcript.Template) (duckgotypes.ApiRequest, error) {
+	apiRequest := officialtypes.APIRequest{
+		Model:  model,
+		Stream: stream,
+	}
+	if request.SystemInstruction != nil {
+		if parts := geminiPartsToParts(request.SystemInstruction.Parts); len(parts) > 0 {
+			apiRequest.AddMessage("system", parts)
+		}
+	}
+	for _, content := range request.Contents {
+		role := "user"
+		if content.Role == "model" {
+			role = "assistant"
+		}
+		if parts := geminiPartsToParts(content.Parts); len(parts) > 0 {
+			apiRequest.AddMessage(role, parts)
+		}
+	}
+
+	duckgoRequest, err := ConvertAPIRequest(apiRequest, tmpl)
+	if err != nil {
+		return duckgotypes.ApiRequest{}, err
+	}
+	if request.GenerationConfig != nil {
+		duckgoRequest.StopSequences = request.GenerationConfig.StopSequences
+		duckgoRequest.MaxTokens = request.GenerationConfig.MaxOutputTokens
+	}
+	return duckgoRequest, nil
+}
+
+func geminiPartsToParts(geminiParts []geminitypes.Part) []any {
+	var parts []any
+	for _, part := range geminiParts {
+		switch {
+		case part.InlineData != nil && !strings.HasPrefix(part.InlineData.MimeType, "image/"):
+			parts = append(parts, map[string]any{
+				"type": "file",
+				"file": map[string]any{"file_data": "data:" + part.InlineData.MimeType + ";base64," + part.InlineData.Data},
+			})
+		case part.InlineData != nil:
+			parts = append(parts, map[string]any{
+				"type": "image_url",
+				"image_url": map[string]any{
+					"url": "data:" + part.InlineData.MimeType + ";base64," + part.InlineData.Data,
+				},
+			})
+		case part.Text != "":
+			parts = append(parts, map[string]any{"type": "text", "text": part.Text})
+		}
+	}
+	return parts
+}
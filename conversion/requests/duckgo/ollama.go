@@ -0,0 +1,80 @@
+package duckgo
+
+import (
+	"aurora/internal/transcript"
+	duckgotypes "aurora/typings/duckgo"
+	officialtypes "aurora/typings/official"
+	ollamatypes "aurora/typings/ollama"
+	"encoding/base64"
+	"net/http"
+)
+
+// ConvertOllamaChatRequest 将 Ollama /api/chat 请求转换为 DuckDuckGo 格式。
+func ConvertOllamaChatRequest(request ollamatypes.ChatRequest, tmpl *transcript.Template) (duckgotypes.ApiRequest, error) {
+	apiRequest := officialtypes.APIRequest{
+		Model:  request.Model,
+		Stream: ollamatypes.IsStream(request.Stream),
+	}
+	for _, msg := range request.Messages {
+		apiRequest.AddMessage(msg.Role, ollamaContent(msg.Content, msg.Images))
+	}
+	return convertOllamaRequest(apiRequest, request.Options, tmpl)
+}
+
+// ConvertOllamaGenerateRequest 将 Ollama /api/generate 请求包装为单轮对话。
+func ConvertOllamaGenerateRequest(request ollamatypes.GenerateRequest, tmpl *transcript.Template) (duckgotypes.ApiRequest, error) {
+	apiRequest := officialtypes.APIRequest{
+		Model:  request.Model,
+		Stream: ollamatypes.IsStream(request.Stream),
+	}
+	if request.System != "" {
+		apiRequest.AddMessage("system", request.System)
+	}
+	apiRequest.AddMessage("user", ollamaContent(request.Prompt, request.Images))
+	return convertOllamaRequest(apiRequest, request.Options, tmpl)
+}
+
+func convertOllamaRequest(apiRequest officialtypes.APIRequest, options *ollamatypes.Options, tmpl *transcript.Template) (duckgotypes.ApiRequest, error) {
+	duckgoRequest, err := ConvertAPIRequest(apiRequest, tmpl)
+	if err != nil {
+		return duckgotypes.ApiRequest{}, err
+	}
+	if options != nil {
+		duckgoRequest.StopSequences = options.Stop
+		// num_predict 为负数时表示不限制
+		if options.NumPredict > 0 {
+			duckgoRequest.MaxTokens = options.NumPredict
+		}
+	}
+	return duckgoRequest, nil
+}
+
+// ollamaContent 将文本和裸 base64 图片组合为 OpenAI 格式的 content。
+// Ollama 不传递图片的 MIME 类型，这里根据解码后的文件头推断。
+func ollamaContent(text string, images []string) any {
+	if len(images) == 0 {
+		return text
+	}
+	parts := []any{map[string]any{"type": "text", "text": text}}
+	for _, image := range images {
+		parts = append(parts, map[string]any{
+			"type": "image_url",
+			"image_url": map[string]any{
+				"url": "data:" + sniffBase64MimeType(image) + ";base64," + image,
+			},
+		})
+	}
+	return parts
+}
+
+func sniffBase64MimeType(data string) string {
+	// 512 字节足够 http.DetectContentType 判断，对应约 684 个 base64 字符
+	if len(data) > 684 {
+		data = data[:684]
+	}
+	header, err := base64.StdEncoding.DecodeString(data[:len(data)/4*4])
+	if err != nil || len(header) == 0 {
+		return "image/png"
+	}
+	return http.DetectContentType(header)
+}
@@ -0,0 +1,34 @@
+package duckgo
+
+import (
+	officialtypes "aurora/typings/official"
+	"encoding/json"
+	"strings"
+)
+
+// buildResponseFormatPrompt 生成要求模型只输出 JSON 的提示词，
+// json_schema 模式下会附带完整的 schema。
+func buildResponseFormatPrompt(apiRequest *officialtypes.APIRequest) string {
+	var b strings.Builder
+	b.WriteString("# Response format\n\n")
+	schema := apiRequest.JSONSchema()
+	if schema == nil {
+		b.WriteString("Respond with a single valid JSON object.\n")
+	} else {
+		format := apiRequest.ResponseFormat.JSONSchema
+		b.WriteString("Respond with a single JSON value that conforms to the following JSON schema")
+		if format.Name != "" {
+			b.WriteString(" (\"" + format.Name + "\")")
+		}
+		b.WriteString(":\n\n")
+		if format.Description != "" {
+			b.WriteString(format.Description + "\n\n")
+		}
+		encoded, _ := json.MarshalIndent(schema, "", "  ")
+		b.Write(encoded)
+		b.WriteString("\n\n")
+		b.WriteString("Include every required property and do not add properties the schema does not allow.\n")
+	}
+	b.WriteString("Output only the JSON itself: no markdown code fences, no explanations before or after it.\n")
+	return b.String()
+}
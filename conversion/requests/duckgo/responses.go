@@ -0,0 +1,106 @@
+package duckgo
+
+import (
+	"aurora/internal/transcript"
+	duckgotypes "aurora/typings/duckgo"
+	officialtypes "aurora/typings/official"
+	"encoding/json"
+)
+
+// ResponsesToAPIRequest 将 Responses API 的 input 展开为 OpenAI Chat 格式的会话。
+// history 是 previous_response_id 对应的历史会话（可为 nil），新的输入追加在其后。
+// 返回的会话不包含 instructions：按照 Responses API 的语义，
+// instructions 只作用于当前请求，不会随 previous_response_id 延续。
+func ResponsesToAPIRequest(request officialtypes.ResponsesRequest, history *officialtypes.APIRequest) officialtypes.APIRequest {
+	conversation := officialtypes.APIRequest{
+		Model:  request.Model,
+		Stream: request.Stream,
+	}
+	if history != nil {
+		conversation.Messages = append(conversation.Messages, history.Messages...)
+	}
+
+	switch input := request.Input.(type) {
+	case string:
+		conversation.AddMessage("user", input)
+	case []any:
+		for _, element := range input {
+			addResponseInputItem(&conversation, element)
+		}
+	}
+	return conversation
+}
+
+// ConvertResponsesRequest 在会话前插入 instructions 后转换为 DuckDuckGo 格式，错误同 ConvertAPIRequest。
+func ConvertResponsesRequest(request officialtypes.ResponsesRequest, conversation officialtypes.APIRequest, tmpl *transcript.Template) (duckgotypes.ApiRequest, error) {
+	apiRequest := officialtypes.APIRequest{
+		Model:           conversation.Model,
+		Stream:          conversation.Stream,
+		MaxTokens:       request.MaxOutputTokens,
+		ReasoningEffort: request.ReasoningEffort(),
+	}
+	if request.Instructions != "" {
+		apiRequest.AddMessage("system", request.Instructions)
+	}
+	apiRequest.Messages = append(apiRequest.Messages, conversation.Messages...)
+	return ConvertAPIRequest(apiRequest, tmpl)
+}
+
+func addResponseInputItem(conversation *officialtypes.APIRequest, element any) {
+	raw, err := json.Marshal(element)
+	if err != nil {
+		return
+	}
+	var item officialtypes.ResponseInputItem
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return
+	}
+	if item.Type != "" && item.Type != "message" {
+		return
+	}
+	if item.Role == "" {
+		return
+	}
+
+	switch content := item.Content.(type) {
+	case string:
+		conversation.AddMessage(item.Role, content)
+	case []any:
+		if parts := responseContentToParts(content); len(parts) > 0 {
+			conversation.AddMessage(item.Role, parts)
+		}
+	}
+}
+
+func responseContentToParts(content []any) []any {
+	var parts []any
+	for _, element := range content {
+		raw, err := json.Marshal(element)
+		if err != nil {
+			continue
+		}
+		var part officialtypes.ResponseContentPart
+		if err := json.Unmarshal(raw, &part); err != nil {
+			continue
+		}
+		switch part.Type {
+		case "input_text", "output_text", "text":
+			parts = append(parts, map[string]any{"type": "text", "text": part.Text})
+		case "input_image":
+			if part.ImageURL != "" {
+				parts = append(parts, map[string]any{
+					"type":      "image_url",
+					"image_url": map[string]any{"url": part.ImageURL},
+				})
+			}
+		case "input_file":
+			if part.FileData != "" {
+				parts = append(parts, map[string]any{
+					"type": "file",
+					"file": map[string]any{"filename": part.Filename, "file_data": part.FileData},
+				})
+			}
+		}
+	}
+	return parts
+}
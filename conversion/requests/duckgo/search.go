@@ -0,0 +1,46 @@
+package duckgo
+
+import (
+	duckgotypes "aurora/typings/duckgo"
+	officialtypes "aurora/typings/official"
+	"fmt"
+	"strings"
+)
+
+// SplitModelSuffix 解析模型名后的工具后缀，例如 gpt-4o-mini:search、gpt-4o-mini:news:weather。
+// 可用的后缀：
+//   - search：网页相关的全部搜索（本地、新闻、视频）
+//   - local / news / videos：单独启用对应的搜索
+//   - weather：天气预报
+func SplitModelSuffix(model string) (base string, tools duckgotypes.Tool, err error) {
+	parts := strings.Split(model, ":")
+	for _, suffix := range parts[1:] {
+		switch strings.ToLower(suffix) {
+		case "search":
+			tools.LocalSearch, tools.NewsSearch, tools.VideosSearch = true, true, true
+		case "local":
+			tools.LocalSearch = true
+		case "news":
+			tools.NewsSearch = true
+		case "videos":
+			tools.VideosSearch = true
+		case "weather":
+			tools.WeatherForecast = true
+		default:
+			return "", duckgotypes.Tool{}, fmt.Errorf("unknown model suffix %q", suffix)
+		}
+	}
+	return parts[0], tools, nil
+}
+
+// applySearchTools 根据模型后缀和 web_search_options 启用 duck.ai 的内置工具。
+func applySearchTools(apiRequest *officialtypes.APIRequest, duckgoRequest *duckgotypes.ApiRequest) {
+	_, tools, _ := SplitModelSuffix(apiRequest.Model)
+	if options := apiRequest.WebSearchOptions; options != nil {
+		tools.LocalSearch, tools.NewsSearch, tools.VideosSearch = true, true, true
+		if location := options.UserLocation; location != nil && location.Approximate != nil {
+			duckgoRequest.CanUseApproxLocation = location.Approximate
+		}
+	}
+	duckgoRequest.Metadata.ToolChoice = tools
+}
@@ -0,0 +1,94 @@
+package duckgo
+
+import (
+	"aurora/internal/transcript"
+	duckgotypes "aurora/typings/duckgo"
+	"strings"
+)
+
+// mergeMessages 在模板定义了 merge 时，把对话中连续的同角色纯文本消息合并为一条，开头固定保留的提示不参与合并。
+func mergeMessages(duckgoRequest *duckgotypes.ApiRequest, tmpl *transcript.Template) {
+	pinned := duckgoRequest.PinnedMessages
+	dialogue := duckgoRequest.Messages[pinned:]
+	merged := append([]any(nil), duckgoRequest.Messages[:pinned]...)
+	for i := 0; i < len(dialogue); {
+		role, text, ok := messageText(dialogue[i])
+		texts := []string{text}
+		j := i + 1
+		for ok && j < len(dialogue) {
+			nextRole, nextText, nextOK := messageText(dialogue[j])
+			if !nextOK || nextRole != role {
+				break
+			}
+			texts = append(texts, nextText)
+			j++
+		}
+		if len(texts) > 1 {
+			if text, ok := tmpl.Merge(role, texts); ok {
+				merged = append(merged, textMessage(role, text))
+				i = j
+				continue
+			}
+		}
+		merged = append(merged, dialogue[i:j]...)
+		i = j
+	}
+	duckgoRequest.Messages = merged
+}
+
+// addPrefill 在对话以助手消息结尾（客户端预填充了回复的开头）且模板定义了 prefill 时，追加一条让模型接着写的用户消息。
+func addPrefill(duckgoRequest *duckgotypes.ApiRequest, tmpl *transcript.Template) {
+	if len(duckgoRequest.Messages) <= duckgoRequest.PinnedMessages {
+		return
+	}
+	last, ok := duckgoRequest.Messages[len(duckgoRequest.Messages)-1].(duckgotypes.MessageAssistant)
+	if !ok {
+		return
+	}
+	if instruction, ok := tmpl.Prefill(partsText(last.Parts)); ok {
+		duckgoRequest.AddMessageUser(instruction)
+	}
+}
+
+// messageText 返回纯文本消息的角色和文本，消息包含图片、文件等其他内容时 ok 为 false。
+func messageText(message any) (role, text string, ok bool) {
+	switch m := message.(type) {
+	case duckgotypes.MessageUser:
+		switch content := m.Content.(type) {
+		case string:
+			return "user", content, true
+		case []any:
+			return "user", partsText(content), textOnly(content)
+		}
+	case duckgotypes.MessageAssistant:
+		return "assistant", partsText(m.Parts), textOnly(m.Parts)
+	}
+	return "", "", false
+}
+
+func textOnly(parts []any) bool {
+	for _, part := range parts {
+		if _, ok := part.(duckgotypes.PartText); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// partsText 拼接消息中的文本，忽略其他内容。
+func partsText(parts []any) string {
+	var texts []string
+	for _, part := range parts {
+		if text, ok := part.(duckgotypes.PartText); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func textMessage(role, text string) any {
+	if role == "assistant" {
+		return duckgotypes.MessageAssistant{Role: "assistant", Parts: []any{duckgotypes.PartText{Type: "text", Text: text}}}
+	}
+	return duckgotypes.MessageUser{Role: "user", Content: text}
+}
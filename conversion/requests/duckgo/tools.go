@@ -0,0 +1,64 @@
+package duckgo
+
+import (
+	"aurora/internal/toolcall"
+	"aurora/internal/transcript"
+	officialtypes "aurora/typings/official"
+	"encoding/json"
+	"strings"
+)
+
+// buildToolPrompt 生成描述可用函数及调用格式的提示词。
+// 上游模型不支持原生函数调用，模型按约定格式输出后由网关解析为 tool_calls。
+// 调用格式由解析器决定（对话模板的 tool_call 部分也必须使用它），结果格式按 tmpl 的 tool_result 部分给出示例。
+func buildToolPrompt(apiRequest *officialtypes.APIRequest, tmpl *transcript.Template) string {
+	var b strings.Builder
+	b.WriteString("# Tools\n\n")
+	b.WriteString("You may call the following functions. Each function is described by a JSON schema:\n\n")
+	b.WriteString("<tools>\n")
+	for _, tool := range apiRequest.Tools {
+		definition, _ := json.Marshal(map[string]any{
+			"name":        tool.Function.Name,
+			"description": tool.Function.Description,
+			"parameters":  tool.Function.Parameters,
+		})
+		b.Write(definition)
+		b.WriteString("\n")
+	}
+	b.WriteString("</tools>\n\n")
+	b.WriteString("To call a function, reply with one block per call in exactly this form:\n")
+	b.WriteString(toolcall.OpenTag + "\n")
+	b.WriteString(`{"name": "<function name>", "arguments": {<arguments as a JSON object>}}` + "\n")
+	b.WriteString(toolcall.CloseTag + "\n\n")
+	b.WriteString("Rules:\n")
+	b.WriteString("- The arguments must be valid JSON that matches the function's schema.\n")
+	b.WriteString("- Do not write anything after the last " + toolcall.CloseTag + " block.\n")
+	b.WriteString("- Function results will be sent back to you in a later message, formatted like this:\n")
+	b.WriteString(tmpl.ToolResult("<call id>", "<function name>", "<result>") + "\n")
+
+	switch {
+	case apiRequest.ForcedToolName() != "":
+		b.WriteString("- You must call the function \"" + apiRequest.ForcedToolName() + "\" in this reply.\n")
+	case apiRequest.ToolChoice == "required":
+		b.WriteString("- You must call at least one function in this reply.\n")
+	default:
+		b.WriteString("- Only call a function when it is needed; otherwise answer the user directly without any " + toolcall.OpenTag + " block.\n")
+	}
+	if apiRequest.ParallelToolCalls != nil && !*apiRequest.ParallelToolCalls {
+		b.WriteString("- Call at most one function per reply.\n")
+	}
+	return b.String()
+}
+
+// formatAssistantToolCalls 把助手消息中的函数调用渲染为文本，追加在助手原有的文本之后，
+// 使上游模型能看到完整的调用历史。
+func formatAssistantToolCalls(text string, toolCalls []officialtypes.ToolCall, tmpl *transcript.Template) string {
+	blocks := []string{}
+	if strings.TrimSpace(text) != "" {
+		blocks = append(blocks, text)
+	}
+	for _, call := range toolCalls {
+		blocks = append(blocks, tmpl.ToolCall(call.ID, call.Function.Name, call.Function.Arguments))
+	}
+	return strings.Join(blocks, "\n\n")
+}
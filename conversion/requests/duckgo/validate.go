@@ -0,0 +1,34 @@
+package duckgo
+
+import "fmt"
+
+// ValidationError 表示请求中的某条消息无法转换，应以 400 invalid_request_error 返回给客户端。
+type ValidationError struct {
+	Index   int    // 消息在 messages 中的下标
+	Field   string // 消息内的字段路径，例如 content[1].image_url.url
+	Message string
+}
+
+// Param 返回出错字段在请求中的完整路径，用作 OpenAI 错误的 param。
+func (e *ValidationError) Param() string {
+	if e.Field == "" {
+		return fmt.Sprintf("messages[%d]", e.Index)
+	}
+	return fmt.Sprintf("messages[%d].%s", e.Index, e.Field)
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid value for '%s': %s", e.Param(), e.Message)
+}
+
+func invalidField(index int, field string, format string, args ...any) *ValidationError {
+	return &ValidationError{Index: index, Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// partField 返回多段内容中第 j 段内字段的路径。
+func partField(j int, field string) string {
+	if field == "" {
+		return fmt.Sprintf("content[%d]", j)
+	}
+	return fmt.Sprintf("content[%d].%s", j, field)
+}
@@ -1,12 +1,14 @@
 package httpclient
 
 import (
+	"context"
 	"io"
 	"net/http"
 )
 
 type AuroraHttpClient interface {
-	Request(method HttpMethod, url string, headers AuroraHeaders, cookies []*http.Cookie, body io.Reader) (*http.Response, error)
+	// Request 发送请求。ctx 结束时请求以及响应体的读取都会被中止。
+	Request(ctx context.Context, method HttpMethod, url string, headers AuroraHeaders, cookies []*http.Cookie, body io.Reader) (*http.Response, error)
 	SetProxy(url string) error
 }
 
@@ -2,6 +2,7 @@ package bogdanfinn
 
 import (
 	"aurora/httpclient"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -37,7 +38,7 @@ func TestTlsClient_Request(t *testing.T) {
 	header.Set("origin", "https://chat.openai.com")
 	header.Set("referer", "https://chat.openai.com/")
 	header.Set("oai-device-id", "c83b24f0-5a9e-4c43-8915-3f67d4332609")
-	response, err := client.Request(http.MethodPost, apiUrl, header, nil, payload)
+	response, err := client.Request(context.Background(), http.MethodPost, apiUrl, header, nil, payload)
 	if err != nil {
 		return
 	}
@@ -63,7 +64,7 @@ func TestChatGPTModel(t *testing.T) {
 	header.Set("origin", "https://chat.openai.com")
 	header.Set("referer", "https://chat.openai.com/")
 	header.Set("oai-device-id", "c83b24f0-5a9e-4c43-8915-3f67d4332609")
-	response, err := client.Request(http.MethodGet, apiUrl, header, nil, nil)
+	response, err := client.Request(context.Background(), http.MethodGet, apiUrl, header, nil, nil)
 	if err != nil {
 		return
 	}
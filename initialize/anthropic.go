@@ -0,0 +1,155 @@
+package initialize
+
+import (
+	duckgoConvert "aurora/conversion/requests/duckgo"
+	"aurora/internal/duckgo"
+	"aurora/logger"
+	anthropictypes "aurora/typings/anthropic"
+	"aurora/util"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// anthropicMessages 处理 Anthropic Messages API (/v1/messages) 请求。
+// 请求被转换为 DuckDuckGo 格式后复用 Provider 和 SSE 读取逻辑，
+// 仅在输出时按照 Anthropic 的事件格式重新封装。
+func (h *Handler) anthropicMessages(c *gin.Context) {
+	var request anthropictypes.MessagesRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(400, anthropictypes.NewErrorResponse("invalid_request_error", "Request body is invalid JSON"))
+		return
+	}
+	if len(request.Messages) == 0 {
+		c.JSON(400, anthropictypes.NewErrorResponse("invalid_request_error", "messages: at least one message is required"))
+		return
+	}
+	bodyJSON, err := json.Marshal(request)
+	if err == nil && bodyJSON != nil {
+		logger.Ctx(c.Request.Context()).Debugf("%s", bodyJSON)
+	}
+	model, err := h.resolveModel(request.Model)
+	if err != nil {
+		if isMissingModel(err) {
+			c.JSON(400, anthropictypes.NewErrorResponse("invalid_request_error", err.Error()))
+			return
+		}
+		c.JSON(404, anthropictypes.NewErrorResponse("not_found_error", err.Error()))
+		return
+	}
+	tmpl, err := h.transcriptTemplate(c, model)
+	if err != nil {
+		c.JSON(400, anthropictypes.NewErrorResponse("invalid_request_error", err.Error()))
+		return
+	}
+	translatedRequest, err := duckgoConvert.ConvertMessagesRequest(request, tmpl)
+	if err != nil {
+		c.JSON(400, anthropictypes.NewErrorResponse("invalid_request_error", err.Error()))
+		return
+	}
+	model.Apply(&translatedRequest)
+	if err := h.prepareRequest(c.Request.Context(), model, &translatedRequest); err != nil {
+		c.JSON(400, anthropictypes.NewErrorResponse("invalid_request_error", err.Error()))
+		return
+	}
+
+	keepAlive := h.startKeepAlive(c, request.Stream)
+	defer keepAlive.Stop()
+	response, err := h.duckgoProvider.PostConversation(c.Request.Context(), translatedRequest)
+	if err != nil {
+		writeAnthropicError(c, 500, "Failed to post conversation to upstream: "+err.Error())
+		return
+	}
+	defer response.Body.Close()
+
+	if upstreamErr := duckgo.CheckResponse(c.Request.Context(), response, h.duckgoProvider); upstreamErr != nil {
+		writeAnthropicError(c, upstreamErr.StatusCode, upstreamErr.Error())
+		return
+	}
+
+	messageID := newID("msg_")
+	inputTokens := duckgo.CountPromptTokens(translatedRequest)
+	if !request.Stream {
+		result := duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(duckgo.StreamDelta) error { return nil })
+		if result.Err != nil {
+			c.JSON(result.Err.StatusCode(), anthropictypes.NewErrorResponse("api_error", result.Err.Error()))
+			return
+		}
+		message := anthropictypes.NewMessagesResponse(messageID, request.Model, result.Text)
+		message.Usage = anthropictypes.Usage{InputTokens: inputTokens, OutputTokens: util.CountToken(result.Text)}
+		stopReason := anthropicStopReason(result)
+		message.StopReason = &stopReason
+		if result.StopSequence != "" {
+			message.StopSequence = &result.StopSequence
+		}
+		c.JSON(200, message)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	writeEvent := func(event anthropictypes.StreamEvent) error {
+		if _, err := c.Writer.WriteString("event: " + event.Type + "\ndata: " + event.String() + "\n\n"); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	if writeEvent(anthropictypes.NewMessageStartEvent(messageID, request.Model, inputTokens)) != nil ||
+		writeEvent(anthropictypes.NewContentBlockStartEvent(0)) != nil {
+		return
+	}
+	var writeErr error
+	result := duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(delta duckgo.StreamDelta) error {
+		// Anthropic 的 thinking 内容块暂不支持，推理内容直接丢弃
+		if delta.Content == "" {
+			return nil
+		}
+		writeErr = writeEvent(anthropictypes.NewContentBlockDeltaEvent(0, delta.Content))
+		return writeErr
+	})
+	if writeErr != nil {
+		return
+	}
+	if result.Err != nil {
+		_ = writeEvent(anthropictypes.NewErrorEvent("api_error", result.Err.Error()))
+		return
+	}
+
+	var stopSequence *string
+	if result.StopSequence != "" {
+		stopSequence = &result.StopSequence
+	}
+	_ = writeEvent(anthropictypes.NewContentBlockStopEvent(0))
+	_ = writeEvent(anthropictypes.NewMessageDeltaEvent(anthropicStopReason(result), stopSequence, anthropictypes.Usage{OutputTokens: util.CountToken(result.Text)}))
+	_ = writeEvent(anthropictypes.NewMessageStopEvent())
+}
+
+// writeAnthropicError 写出 Anthropic 格式的 api_error。
+// 心跳已经写出 SSE 响应头时，改为发送 error 事件。
+func writeAnthropicError(c *gin.Context, status int, message string) {
+	if !c.Writer.Written() {
+		c.JSON(status, anthropictypes.NewErrorResponse("api_error", message))
+		return
+	}
+	event := anthropictypes.NewErrorEvent("api_error", message)
+	c.Writer.WriteString("event: " + event.Type + "\ndata: " + event.String() + "\n\n")
+	c.Writer.Flush()
+}
+
+// anthropicStopReason 将网关的结束原因映射为 Anthropic 的 stop_reason。
+func anthropicStopReason(result duckgo.StreamResult) string {
+	switch {
+	case result.StopSequence != "":
+		return anthropictypes.StopReasonStopSequence
+	case result.FinishReason == "length":
+		return anthropictypes.StopReasonMaxTokens
+	default:
+		return anthropictypes.StopReasonEndTurn
+	}
+}
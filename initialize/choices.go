@@ -0,0 +1,107 @@
+package initialize
+
+import (
+	duckgoConvert "aurora/conversion/requests/duckgo"
+	"aurora/internal/duckgo"
+	"aurora/logger"
+	duckgotypes "aurora/typings/duckgo"
+	officialtypes "aurora/typings/official"
+	"aurora/util"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// choiceResult 是 n > 1 时单个 choice 的结果。
+type choiceResult struct {
+	result duckgo.StreamResult
+	err    error // 会话失败时的错误，可能是 *duckgo.UpstreamError 或 *duckgo.StreamError
+}
+
+// multipleChoices 处理 n > 1 的请求：为每个 choice 发起一次独立的上游会话，
+// 同时进行的会话数受 choicesLimit 限制。流式响应中各 choice 的 chunk 交错输出，
+// 以 choices[].index 区分；非流式响应汇总为一个包含多个 choice 的 ChatCompletion。
+func (h *Handler) multipleChoices(c *gin.Context, original_request officialtypes.APIRequest, translatedRequest duckgotypes.ApiRequest) {
+	log := logger.Ctx(c.Request.Context())
+	n := original_request.N
+	meta := newResponseMeta("chatcmpl-", translatedRequest.Model)
+	results := make([]choiceResult, n)
+
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, h.choicesLimit)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			request := duckgoConvert.CloneRequest(translatedRequest)
+			response, err := h.duckgoProvider.PostConversation(c.Request.Context(), request)
+			if err != nil {
+				results[index].err = err
+				return
+			}
+			defer response.Body.Close()
+			if upstreamErr := duckgo.CheckResponse(c.Request.Context(), response, h.duckgoProvider); upstreamErr != nil {
+				results[index].err = upstreamErr
+				return
+			}
+
+			options := h.streamOptions(c.Request.Context(), original_request, request, meta)
+			options.IncludeUsage = false
+			options.Index = index
+			options.Lock = &writeMu
+			results[index].result = duckgo.StreamHandler(c, response, request, options)
+			if streamErr := results[index].result.Err; streamErr != nil {
+				results[index].err = streamErr
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	usage := officialtypes.NewUsage(duckgo.CountPromptTokens(translatedRequest), 0)
+	var firstErr error
+	started := false // 是否有 choice 已经开始输出，中途出错的 choice 已经发送过错误事件
+	for i, choice := range results {
+		if _, ok := choice.err.(*duckgo.StreamError); choice.err == nil || ok {
+			started = true
+		}
+		if choice.err != nil {
+			log.Warnf("Choice %d of %d failed: %v", i, n, choice.err)
+			if firstErr == nil {
+				firstErr = choice.err
+			}
+			continue
+		}
+		usage = officialtypes.NewUsage(usage.PromptTokens, usage.CompletionTokens+util.CountToken(choice.result.CompletionText()))
+	}
+
+	if original_request.Stream {
+		// 已经输出过的 choice 无法撤回，只有全部失败时才返回错误
+		if !started {
+			writeConversationError(c, firstErr)
+			return
+		}
+		if original_request.IncludeUsage() {
+			usageChunk := officialtypes.UsageChunk(meta, usage)
+			c.Writer.WriteString("data: " + usageChunk.String() + "\n\n")
+			c.Writer.Flush()
+		}
+		return
+	}
+
+	// 非流式请求需要全部 choice 都成功
+	if firstErr != nil {
+		writeConversationError(c, firstErr)
+		return
+	}
+	completion := newChatCompletion(meta, results[0].result, usage)
+	for i := 1; i < n; i++ {
+		choice := newChatCompletion(meta, results[i].result, usage).Choices[0]
+		choice.Index = i
+		completion.Choices = append(completion.Choices, choice)
+	}
+	c.JSON(200, completion)
+}
@@ -0,0 +1,183 @@
+package initialize
+
+import (
+	duckgoConvert "aurora/conversion/requests/duckgo"
+	"aurora/internal/duckgo"
+	"aurora/logger"
+	officialtypes "aurora/typings/official"
+	"aurora/util"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// completions 处理旧版文本补全接口 (/v1/completions)。
+// 每个 prompt 被包装为一条用户消息单独发送到上游，结果以 text_completion 格式返回。
+func (h *Handler) completions(c *gin.Context) {
+	var request officialtypes.CompletionRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": gin.H{
+			"message": "Request body is invalid JSON",
+			"type":    "invalid_request_error",
+		}})
+		return
+	}
+	prompts, ok := request.Prompts()
+	if !ok {
+		c.JSON(400, gin.H{"error": gin.H{
+			"message": "prompt must be a string or an array of strings",
+			"type":    "invalid_request_error",
+			"param":   "prompt",
+		}})
+		return
+	}
+	bodyJSON, err := json.Marshal(request)
+	if err == nil && bodyJSON != nil {
+		logger.Ctx(c.Request.Context()).Debugf("%s", bodyJSON)
+	}
+
+	model, err := h.resolveModel(request.Model)
+	if err != nil {
+		writeModelNotFound(c, err)
+		return
+	}
+	tmpl, err := h.transcriptTemplate(c, model)
+	if err != nil {
+		writeInvalidParam(c, TemplateHeader, err)
+		return
+	}
+
+	keepAlive := h.startKeepAlive(c, request.Stream)
+	defer keepAlive.Stop()
+	meta := newResponseMeta("cmpl-", request.Model)
+	choices := make([]officialtypes.TextChoice, 0, len(prompts))
+	var promptTokens, completionTokens int
+
+	for index, prompt := range prompts {
+		translatedRequest, err := duckgoConvert.ConvertCompletionRequest(request, prompt, tmpl)
+		if err != nil {
+			if index == 0 || !request.Stream {
+				writeValidationError(c, err)
+				return
+			}
+			abortCompletionStream(c, index, &duckgo.StreamError{Type: "invalid_prompt", Message: err.Error()})
+			return
+		}
+		model.Apply(&translatedRequest)
+		if err := h.prepareRequest(c.Request.Context(), model, &translatedRequest); err != nil {
+			if index == 0 || !request.Stream {
+				writePrepareError(c, "prompt", err)
+				return
+			}
+			abortCompletionStream(c, index, &duckgo.StreamError{Type: "invalid_prompt", Message: err.Error()})
+			return
+		}
+
+		var response *http.Response
+		if index == 0 || !request.Stream {
+			if response = h.postConversation(c, translatedRequest); response == nil {
+				return
+			}
+		} else {
+			// 流式输出已经开始，此时无法再写出 JSON 错误，只能以 error 事件结束。
+			var err error
+			response, err = h.duckgoProvider.PostConversation(c.Request.Context(), translatedRequest)
+			if err != nil {
+				streamErr, ok := err.(*duckgo.StreamError)
+				if !ok {
+					streamErr = &duckgo.StreamError{Type: "upstream_request_failed", Message: err.Error()}
+				}
+				abortCompletionStream(c, index, streamErr)
+				return
+			}
+			if upstreamErr := duckgo.CheckResponse(c.Request.Context(), response, h.duckgoProvider); upstreamErr != nil {
+				response.Body.Close()
+				abortCompletionStream(c, index, &duckgo.StreamError{Type: "upstream_error", Status: upstreamErr.StatusCode, Message: upstreamErr.Error()})
+				return
+			}
+		}
+
+		promptTokens += duckgo.CountPromptTokens(translatedRequest)
+		if !request.Stream {
+			result := duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(duckgo.StreamDelta) error { return nil })
+			response.Body.Close()
+			if result.Err != nil {
+				duckgo.WriteStreamError(c, result.Err)
+				return
+			}
+			completionTokens += util.CountToken(result.Text)
+			text := result.Text
+			if request.Echo {
+				text = prompt + text
+			}
+			choices = append(choices, officialtypes.TextChoice{Text: text, Index: index, FinishReason: result.FinishReason})
+			continue
+		}
+
+		if index == 0 {
+			c.Header("Content-Type", "text/event-stream; charset=utf-8")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("Connection", "keep-alive")
+			c.Status(http.StatusOK)
+		}
+		writeChunk := func(chunk officialtypes.TextCompletion) error {
+			if _, err := c.Writer.WriteString("data: " + chunk.String() + "\n\n"); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			return nil
+		}
+
+		var writeErr error
+		if request.Echo {
+			writeErr = writeChunk(officialtypes.NewTextCompletionChunk(meta, index, prompt, nil))
+		}
+		if writeErr == nil {
+			result := duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(delta duckgo.StreamDelta) error {
+				if delta.Content == "" {
+					return nil
+				}
+				writeErr = writeChunk(officialtypes.NewTextCompletionChunk(meta, index, delta.Content, nil))
+				return writeErr
+			})
+			completionTokens += util.CountToken(result.Text)
+			if writeErr == nil && result.Err != nil {
+				c.Writer.WriteString(duckgo.StreamErrorEvent(result.Err))
+				c.Writer.Flush()
+				response.Body.Close()
+				return
+			}
+			if writeErr == nil {
+				writeErr = writeChunk(officialtypes.NewTextCompletionChunk(meta, index, "", result.FinishReason))
+			}
+		}
+		response.Body.Close()
+		if writeErr != nil {
+			return
+		}
+	}
+
+	usage := officialtypes.NewUsage(promptTokens, completionTokens)
+	if request.Stream {
+		if request.StreamOptions != nil && request.StreamOptions.IncludeUsage {
+			usageChunk := officialtypes.NewTextCompletion(meta, []officialtypes.TextChoice{})
+			usageChunk.Usage = &usage
+			c.Writer.WriteString("data: " + usageChunk.String() + "\n\n")
+		}
+		c.Writer.WriteString("data: [DONE]\n\n")
+		c.Writer.Flush()
+		return
+	}
+	completion := officialtypes.NewTextCompletion(meta, choices)
+	completion.Usage = &usage
+	c.JSON(200, completion)
+}
+
+// abortCompletionStream 在流式输出已经开始后报告第 index 个 prompt 的失败，
+// 与上游中途出错一样以 error 事件结束响应，不再发送 [DONE]。
+func abortCompletionStream(c *gin.Context, index int, streamErr *duckgo.StreamError) {
+	logger.Ctx(c.Request.Context()).Errorf("Completion prompt %d failed: %v", index, streamErr)
+	c.Writer.WriteString(duckgo.StreamErrorEvent(streamErr))
+	c.Writer.Flush()
+}
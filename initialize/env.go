@@ -0,0 +1,55 @@
+package initialize
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getIntFromEnv 从环境变量读取正整数，未设置或非法时返回默认值。
+func getIntFromEnv(key string, defaultValue int) int {
+	if valStr := os.Getenv(key); valStr != "" {
+		if valInt, err := strconv.Atoi(valStr); err == nil && valInt > 0 {
+			return valInt
+		}
+	}
+	return defaultValue
+}
+
+// getDurationFromEnv 从环境变量读取时间（秒），未设置或非法时返回默认值。
+func getDurationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	if valInt := getIntFromEnv(key, 0); valInt > 0 {
+		return time.Duration(valInt) * time.Second
+	}
+	return defaultValue
+}
+
+// getNonNegativeIntFromEnv 从环境变量读取非负整数（允许 0），未设置或非法时返回默认值。
+func getNonNegativeIntFromEnv(key string, defaultValue int) int {
+	if valStr := os.Getenv(key); valStr != "" {
+		if valInt, err := strconv.Atoi(valStr); err == nil && valInt >= 0 {
+			return valInt
+		}
+	}
+	return defaultValue
+}
+
+// getStringFromEnv 从环境变量读取字符串，未设置时返回默认值。
+func getStringFromEnv(key string, defaultValue string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultValue
+}
+
+// getListFromEnv 从环境变量读取以逗号分隔的列表，忽略空项。
+func getListFromEnv(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
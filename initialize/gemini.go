@@ -0,0 +1,170 @@
+package initialize
+
+import (
+	duckgoConvert "aurora/conversion/requests/duckgo"
+	"aurora/internal/duckgo"
+	"aurora/logger"
+	geminitypes "aurora/typings/gemini"
+	"aurora/util"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// geminiGenerateContent 处理 Gemini 风格的 /v1beta/models/{model}:generateContent
+// 和 :streamGenerateContent 请求。模型名与方法名共用一个路径段，需要手动拆分。
+func (h *Handler) geminiGenerateContent(c *gin.Context) {
+	model, method, found := strings.Cut(strings.TrimPrefix(c.Param("action"), "/"), ":")
+	if !found || model == "" || (method != "generateContent" && method != "streamGenerateContent") {
+		c.JSON(404, geminitypes.NewErrorResponse(404, "NOT_FOUND", "Method not found: "+c.Param("action")))
+		return
+	}
+	stream := method == "streamGenerateContent"
+
+	var request geminitypes.GenerateContentRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(400, geminitypes.NewErrorResponse(400, "INVALID_ARGUMENT", "Request body is invalid JSON"))
+		return
+	}
+	if len(request.Contents) == 0 {
+		c.JSON(400, geminitypes.NewErrorResponse(400, "INVALID_ARGUMENT", "contents is not specified"))
+		return
+	}
+	bodyJSON, err := json.Marshal(request)
+	if err == nil && bodyJSON != nil {
+		logger.Ctx(c.Request.Context()).Debugf("%s", bodyJSON)
+	}
+	registryModel, err := h.resolveModel(model)
+	if err != nil {
+		c.JSON(404, geminitypes.NewErrorResponse(404, "NOT_FOUND", err.Error()))
+		return
+	}
+	tmpl, err := h.transcriptTemplate(c, registryModel)
+	if err != nil {
+		c.JSON(400, geminitypes.NewErrorResponse(400, "INVALID_ARGUMENT", err.Error()))
+		return
+	}
+	translatedRequest, err := duckgoConvert.ConvertGenerateContentRequest(model, request, stream, tmpl)
+	if err != nil {
+		c.JSON(400, geminitypes.NewErrorResponse(400, "INVALID_ARGUMENT", err.Error()))
+		return
+	}
+	registryModel.Apply(&translatedRequest)
+	if err := h.prepareRequest(c.Request.Context(), registryModel, &translatedRequest); err != nil {
+		c.JSON(400, geminitypes.NewErrorResponse(400, "INVALID_ARGUMENT", err.Error()))
+		return
+	}
+
+	// 默认以流式 JSON 数组输出，无法插入心跳；?alt=sse 时使用 SSE 并发送心跳。
+	sse := stream && c.Query("alt") == "sse"
+	keepAlive := h.startKeepAlive(c, sse)
+	defer keepAlive.Stop()
+	response, err := h.duckgoProvider.PostConversation(c.Request.Context(), translatedRequest)
+	if err != nil {
+		writeGeminiError(c, 500, "INTERNAL", "Failed to post conversation to upstream: "+err.Error())
+		return
+	}
+	defer response.Body.Close()
+
+	if upstreamErr := duckgo.CheckResponse(c.Request.Context(), response, h.duckgoProvider); upstreamErr != nil {
+		writeGeminiError(c, upstreamErr.StatusCode, "UNAVAILABLE", upstreamErr.Error())
+		return
+	}
+
+	promptTokens := duckgo.CountPromptTokens(translatedRequest)
+	if !stream {
+		result := duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(duckgo.StreamDelta) error { return nil })
+		if result.Err != nil {
+			status := result.Err.StatusCode()
+			c.JSON(status, geminitypes.NewErrorResponse(status, "UNAVAILABLE", result.Err.Error()))
+			return
+		}
+		resp := geminitypes.NewGenerateContentResponse(result.Text, geminiFinishReason(result), model)
+		resp.UsageMetadata = geminitypes.NewUsageMetadata(promptTokens, util.CountToken(result.Text))
+		c.JSON(200, resp)
+		return
+	}
+
+	if sse {
+		c.Header("Content-Type", "text/event-stream; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	first := true
+	writeResponse := func(resp geminitypes.GenerateContentResponse) error {
+		var payload string
+		switch {
+		case sse:
+			payload = "data: " + resp.String() + "\r\n\r\n"
+		case first:
+			payload = "[" + resp.String()
+		default:
+			payload = ",\r\n" + resp.String()
+		}
+		first = false
+		if _, err := c.Writer.WriteString(payload); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	var writeErr error
+	result := duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(delta duckgo.StreamDelta) error {
+		if delta.Content == "" {
+			return nil
+		}
+		writeErr = writeResponse(geminitypes.NewGenerateContentResponse(delta.Content, "", model))
+		return writeErr
+	})
+	if writeErr != nil {
+		return
+	}
+	if result.Err != nil {
+		// 流中的错误对象与普通响应一样作为 SSE 事件或数组元素输出
+		status := result.Err.StatusCode()
+		errResp := geminitypes.NewErrorResponse(status, "UNAVAILABLE", result.Err.Error())
+		payload := "data: " + errResp.String() + "\r\n\r\n"
+		if !sse {
+			payload = ",\r\n" + errResp.String() + "]"
+			if first {
+				payload = "[" + errResp.String() + "]"
+			}
+		}
+		c.Writer.WriteString(payload)
+		c.Writer.Flush()
+		return
+	}
+
+	final := geminitypes.NewGenerateContentResponse("", geminiFinishReason(result), model)
+	final.UsageMetadata = geminitypes.NewUsageMetadata(promptTokens, util.CountToken(result.Text))
+	if writeResponse(final) == nil && !sse {
+		c.Writer.WriteString("]")
+		c.Writer.Flush()
+	}
+}
+
+// geminiFinishReason 将网关的结束原因映射为 Gemini 的 finishReason。
+func geminiFinishReason(result duckgo.StreamResult) string {
+	if result.FinishReason == "length" {
+		return geminitypes.FinishReasonMaxTokens
+	}
+	return geminitypes.FinishReasonStop
+}
+
+// writeGeminiError 写出 Gemini 格式的错误；SSE 心跳已经写出响应头时以 SSE 事件发送。
+func writeGeminiError(c *gin.Context, status int, code string, message string) {
+	errResp := geminitypes.NewErrorResponse(status, code, message)
+	if !c.Writer.Written() {
+		c.JSON(status, errResp)
+		return
+	}
+	c.Writer.WriteString("data: " + errResp.String() + "\r\n\r\n")
+	c.Writer.Flush()
+}
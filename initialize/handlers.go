@@ -4,13 +4,27 @@ import (
 	duckgoConvert "aurora/conversion/requests/duckgo"
 	"aurora/httpclient/bogdanfinn"
 	"aurora/internal/duckgo"
+	"aurora/internal/files"
+	"aurora/internal/images"
+	"aurora/internal/models"
 	"aurora/internal/proxys"
+	"aurora/internal/responses"
+	"aurora/internal/toolcall"
+	"aurora/internal/transcript"
 	"aurora/logger"
+	duckgotypes "aurora/typings/duckgo"
 	officialtypes "aurora/typings/official"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // Handler 结构体现在直接依赖于 duckgo.Provider。
@@ -18,6 +32,19 @@ import (
 // 包括 HTTP 客户端、代理、以及 Token 的获取和缓存。
 type Handler struct {
 	duckgoProvider *duckgo.Provider
+	responseStore  *responses.Store // 保存 /v1/responses 的历史响应
+	jsonMaxRetries int              // response_format 校验失败后重新请求的次数
+	models         *models.Registry // 可用模型及其别名、能力和默认参数
+	maxChoices     int              // 单个请求允许的最大 n
+	choicesLimit   int              // n > 1 时同时进行的上游会话数
+	keepAlive      time.Duration    // 流式请求等待首个内容时的心跳间隔，0 表示关闭
+	statusEvents   bool             // 心跳期间是否发送 duck2api.status 进度事件
+	// contextStrategy 是模型未配置 context_strategy 时超出上下文长度的裁剪策略
+	contextStrategy string
+	images          *images.Processor // 下载远程图片并转换为上游可接受的格式
+	files           files.Options     // 文件大小和注入文本长度的默认限制
+	templates       *transcript.Store // 对话模板，按模型配置或请求头选择
+	systemTemplates systemTemplateCache
 }
 
 // NewHandler 是 Handler 的构造函数。
@@ -38,16 +65,97 @@ func NewHandler(proxy *proxys.IProxy) (*Handler, error) {
 	}
 
 	logger.Debugf("Provider initialized successfully.")
+
+	// 4. 加载模型注册表，未配置 MODELS_CONFIG 时使用内置模型列表
+	registry := models.NewDefaultRegistry()
+	if path := os.Getenv("MODELS_CONFIG"); path != "" {
+		if registry, err = models.Load(path); err != nil {
+			return nil, err
+		}
+	}
+
+	contextStrategy := getStringFromEnv("CONTEXT_STRATEGY", models.ContextDropOldest)
+	if err := models.ValidateContextStrategy(contextStrategy); err != nil {
+		return nil, fmt.Errorf("invalid CONTEXT_STRATEGY: %w", err)
+	}
+
+	templates, err := newTemplateStore(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. 定期从 duck.ai 发现可用模型，补充到 /v1/models 列表中
+	if interval := getNonNegativeIntFromEnv("MODEL_DISCOVERY_SECONDS", 3600); interval > 0 {
+		go provider.WatchModels(context.Background(), time.Duration(interval)*time.Second, func(discovered []duckgo.DiscoveredModel) {
+			registry.SetDiscovered(discoveredModels(discovered))
+			ids := make([]string, len(discovered))
+			for i, model := range discovered {
+				ids[i] = model.ID
+			}
+			if missing := registry.MissingUpstream(ids); len(missing) > 0 {
+				logger.Warnf("Configured models not available upstream: %s", strings.Join(missing, ", "))
+			}
+		})
+	}
+
 	return &Handler{
 		duckgoProvider: provider,
+		models:         registry,
+		responseStore: responses.NewStore(
+			getDurationFromEnv("RESPONSES_STORE_SECONDS", time.Hour),
+			getIntFromEnv("RESPONSES_STORE_MAX_ENTRIES", 1000),
+		),
+		jsonMaxRetries:  getNonNegativeIntFromEnv("JSON_MODE_MAX_RETRIES", 2),
+		maxChoices:      getIntFromEnv("MAX_CHOICES", 8),
+		choicesLimit:    getIntFromEnv("CHOICES_CONCURRENCY", 3),
+		keepAlive:       time.Duration(getNonNegativeIntFromEnv("SSE_HEARTBEAT_SECONDS", 10)) * time.Second,
+		statusEvents:    os.Getenv("SSE_STATUS_EVENTS") == "1",
+		contextStrategy: contextStrategy,
+		images: images.NewProcessor(images.Options{
+			MaxFetchBytes: int64(getIntFromEnv("IMAGE_FETCH_MAX_BYTES", 10<<20)),
+			Timeout:       getDurationFromEnv("IMAGE_FETCH_TIMEOUT_SECONDS", 15*time.Second),
+			AllowHosts:    getListFromEnv("IMAGE_ALLOW_HOSTS"),
+			DenyHosts:     getListFromEnv("IMAGE_DENY_HOSTS"),
+			AllowPrivate:  os.Getenv("IMAGE_ALLOW_PRIVATE") == "1",
+			MaxDimension:  getNonNegativeIntFromEnv("IMAGE_MAX_DIMENSION", 2048),
+			MaxBytes:      getNonNegativeIntFromEnv("IMAGE_MAX_BYTES", 4<<20),
+		}),
+		files: files.Options{
+			MaxBytes:  getNonNegativeIntFromEnv("FILE_MAX_BYTES", 20<<20),
+			MaxTokens: getNonNegativeIntFromEnv("FILE_MAX_TOKENS", 32000),
+		},
+		templates: templates,
 	}, nil
 }
 
+// newID 生成带前缀的随机 ID，例如 resp_xxx、msg_xxx。
+func newID(prefix string) string {
+	return prefix + strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// newResponseMeta 为一次响应生成唯一 ID 和创建时间，同一响应的所有 chunk 共用。
+func newResponseMeta(prefix string, model string) officialtypes.ResponseMeta {
+	return officialtypes.ResponseMeta{
+		ID:      newID(prefix),
+		Created: time.Now().Unix(),
+		Model:   model,
+	}
+}
+
+// startKeepAlive 为流式请求提前写出 SSE 响应头并开始发送心跳；非流式请求或关闭心跳时返回 nil。
+// 调用方需要在返回前 Stop，第一次写出响应内容时心跳也会自动停止。
+func (h *Handler) startKeepAlive(c *gin.Context, stream bool) *duckgo.KeepAlive {
+	if !stream || h.keepAlive <= 0 {
+		return nil
+	}
+	return duckgo.StartKeepAlive(c, h.keepAlive, h.statusEvents)
+}
+
 // optionsHandler 处理浏览器的 CORS 预检请求。
 func optionsHandler(c *gin.Context) {
 	c.Header("Access-Control-Allow-Origin", "*")
 	c.Header("Access-Control-Allow-Methods", "POST, OPTIONS")
-	c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Api-Key, X-Goog-Api-Key, Anthropic-Version, X-Request-ID, "+TemplateHeader)
 	c.JSON(200, gin.H{"status": "ok"})
 }
 
@@ -64,55 +172,188 @@ func (h *Handler) duckduckgo(c *gin.Context) {
 	}
 	bodyJSON, err := json.Marshal(original_request)
 	if err == nil && bodyJSON != nil {
-		logger.Debugf(string(bodyJSON))
+		logger.Ctx(c.Request.Context()).Debugf("%s", bodyJSON)
+	}
+	if original_request.N < 0 || original_request.N > h.maxChoices {
+		c.JSON(400, gin.H{"error": gin.H{
+			"message": fmt.Sprintf("n must be between 1 and %d", h.maxChoices),
+			"type":    "invalid_request_error",
+			"param":   "n",
+		}})
+		return
+	}
+	if options := original_request.WebSearchOptions; options != nil {
+		switch options.SearchContextSize {
+		case "", "low", "medium", "high":
+		default:
+			writeInvalidParam(c, "web_search_options.search_context_size",
+				fmt.Errorf("Invalid value '%s': expected one of low, medium, high.", options.SearchContextSize))
+			return
+		}
+	}
+	model, err := h.resolveModel(original_request.Model)
+	if err != nil {
+		writeModelNotFound(c, err)
+		return
+	}
+	if err := model.ValidateReasoningEffort(original_request.ReasoningEffort); err != nil {
+		writeInvalidParam(c, "reasoning_effort", err)
+		return
+	}
+	if original_request.ToolsEnabled() && !model.Capabilities.Tools {
+		writeInvalidParam(c, "tools", fmt.Errorf("The model `%s` does not support tools.", model.ID))
+		return
+	}
+	tmpl, err := h.transcriptTemplate(c, model)
+	if err != nil {
+		writeInvalidParam(c, TemplateHeader, err)
+		return
 	}
 	// 将 OpenAI 格式的请求转换为 DuckDuckGo 格式
-	translatedRequest := duckgoConvert.ConvertAPIRequest(original_request)
-
-	// 调用 Provider 的方法来处理会话。
-	// Token 获取、缓存、刷新等所有复杂逻辑都在 Provider 内部自动完成。
-	response, err := h.duckgoProvider.PostConversation(translatedRequest)
+	translatedRequest, err := duckgoConvert.ConvertAPIRequest(original_request, tmpl)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to post conversation to upstream: " + err.Error()})
+		writeValidationError(c, err)
+		return
+	}
+	model.Apply(&translatedRequest)
+	if err := h.prepareRequest(c.Request.Context(), model, &translatedRequest); err != nil {
+		writePrepareError(c, "messages", err)
+		return
+	}
+	keepAlive := h.startKeepAlive(c, original_request.Stream)
+	defer keepAlive.Stop()
+	if original_request.N > 1 {
+		h.multipleChoices(c, original_request, translatedRequest)
 		return
 	}
-	defer response.Body.Close()
 
-	// 使用重构后的错误处理函数
-	if duckgo.HandleRequestError(c, response, h.duckgoProvider) {
+	// 调用 Provider 的方法来处理会话。
+	// Token 获取、缓存、刷新等所有复杂逻辑都在 Provider 内部自动完成。
+	response := h.postConversation(c, translatedRequest)
+	if response == nil {
 		return
 	}
+	defer response.Body.Close()
 	stream := original_request.Stream
 	// 非流式：一次性读取所有消息片段并聚合成完整响应
-	fullMessage := duckgo.StreamHandler(c, response, translatedRequest, stream)
+	meta := newResponseMeta("chatcmpl-", translatedRequest.Model)
+	options := h.streamOptions(c.Request.Context(), original_request, translatedRequest, meta)
+	result := duckgo.StreamHandler(c, response, translatedRequest, options)
 	// 根据请求决定是流式响应还是聚合响应
 	if !stream {
-		c.JSON(200, officialtypes.NewChatCompletionWithModel(fullMessage, translatedRequest.Model))
+		if result.Err != nil {
+			duckgo.WriteStreamError(c, result.Err)
+			return
+		}
+		usage := duckgo.NewUsage(translatedRequest, result.CompletionText())
+		c.JSON(200, newChatCompletion(meta, result, usage))
 	}
 }
 
-// engines 返回支持的模型列表。
-func (h *Handler) engines(c *gin.Context) {
-	// 此处为了简洁，直接硬编码模型列表。
-	// 在实际应用中，可以考虑从配置或 Provider 中获取。
-	supportedModels := []string{
-		"gpt-4o-mini",
-		"gpt-5-mini",
-		"claude-haiku-4-5",
-	}
-
-	data := make([]gin.H, len(supportedModels))
-	for i, modelID := range supportedModels {
-		data[i] = gin.H{
-			"id":       modelID,
-			"object":   "model",
-			"created":  1685474247, // 使用一个固定的时间戳
-			"owned_by": "duckai",
+// streamOptions 根据原始请求构造 StreamHandler 的选项，包括工具调用和 JSON 校验的重试。
+func (h *Handler) streamOptions(ctx context.Context, original_request officialtypes.APIRequest, translatedRequest duckgotypes.ApiRequest, meta officialtypes.ResponseMeta) duckgo.StreamOptions {
+	options := duckgo.StreamOptions{
+		Stream:       original_request.Stream,
+		IncludeUsage: original_request.IncludeUsage(),
+		Meta:         meta,
+	}
+	if original_request.ToolsEnabled() {
+		options.Tools = &duckgo.ToolOptions{
+			Tools: original_request.Tools,
+			Retry: func(previous string, parseErr error) (string, error) {
+				return h.followUp(ctx, translatedRequest, previous, toolCallRepairInstruction(parseErr))
+			},
+		}
+	}
+	if original_request.JSONMode() {
+		options.JSON = &duckgo.JSONOptions{
+			Schema:     original_request.JSONSchema(),
+			MaxRetries: h.jsonMaxRetries,
+			Retry: func(previous string, validationErr error) (string, error) {
+				return h.followUp(ctx, translatedRequest, previous, jsonRepairInstruction(validationErr))
+			},
 		}
 	}
+	return options
+}
+
+// newChatCompletion 根据上游结果构造非流式响应。
+func newChatCompletion(meta officialtypes.ResponseMeta, result duckgo.StreamResult, usage officialtypes.Usage) officialtypes.ChatCompletion {
+	var completion officialtypes.ChatCompletion
+	if len(result.ToolCalls) > 0 {
+		completion = officialtypes.NewToolCallCompletion(meta, result.Text, toolcall.ToOpenAI(result.ToolCalls), usage)
+	} else {
+		completion = officialtypes.NewChatCompletion(meta, result.Text, result.FinishReason, usage)
+	}
+	completion.Choices[0].Message.ReasoningContent = result.Reasoning
+	completion.Choices[0].Message.Annotations = result.Annotations()
+	return completion
+}
+
+// postConversation 通过 Provider 发送会话请求。
+// 请求失败或上游返回错误状态时，会直接写出 OpenAI 格式的错误响应并返回 nil。
+func (h *Handler) postConversation(c *gin.Context, request duckgotypes.ApiRequest) *http.Response {
+	response, err := h.duckgoProvider.PostConversation(c.Request.Context(), request)
+	if err != nil {
+		writeConversationError(c, err)
+		return nil
+	}
+	if duckgo.HandleRequestError(c, response, h.duckgoProvider) {
+		response.Body.Close()
+		return nil
+	}
+	return response
+}
+
+// writeConversationError 写出发起上游会话失败时的错误响应。
+func writeConversationError(c *gin.Context, err error) {
+	switch err := err.(type) {
+	case *duckgo.UpstreamError:
+		duckgo.WriteUpstreamError(c, err)
+		return
+	case *duckgo.StreamError:
+		duckgo.WriteStreamError(c, err)
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		duckgo.WriteError(c, 504, "Upstream conversation timed out: "+err.Error())
+		return
+	}
+	duckgo.WriteError(c, 500, "Failed to post conversation to upstream: "+err.Error())
+}
+
+// followUp 在原会话后追加模型的上一轮输出和一条纠正指令，重新向上游请求一次完整回复。
+// 用于模型输出不符合要求（例如工具调用格式错误）时的自动重试。
+func (h *Handler) followUp(ctx context.Context, request duckgotypes.ApiRequest, previous string, instruction string) (string, error) {
+	return h.complete(ctx, duckgoConvert.NewFollowUpRequest(request, previous, instruction))
+}
+
+// complete 向上游发起一次会话并返回完整回复，不向客户端输出任何内容。
+func (h *Handler) complete(ctx context.Context, request duckgotypes.ApiRequest) (string, error) {
+	response, err := h.duckgoProvider.PostConversation(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if upstreamErr := duckgo.CheckResponse(ctx, response, h.duckgoProvider); upstreamErr != nil {
+		return "", upstreamErr
+	}
+	result := duckgo.ReadStream(ctx, response.Body, request, func(duckgo.StreamDelta) error { return nil })
+	if result.Err != nil {
+		return "", result.Err
+	}
+	return result.Text, nil
+}
+
+// toolCallRepairInstruction 生成让模型重新输出工具调用的指令。
+func toolCallRepairInstruction(parseErr error) string {
+	return "Your previous reply contained a malformed function call (" + parseErr.Error() + "). " +
+		"Reply again with only the corrected " + toolcall.OpenTag + " blocks, using valid JSON arguments that match the function schema."
+}
 
-	c.JSON(200, gin.H{
-		"object": "list",
-		"data":   data,
-	})
+// jsonRepairInstruction 生成让模型按 response_format 重新输出 JSON 的指令。
+func jsonRepairInstruction(validationErr error) string {
+	return "Your previous reply was rejected: " + validationErr.Error() + ". " +
+		"Reply again with only the corrected JSON, without markdown code fences or any other text."
 }
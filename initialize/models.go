@@ -0,0 +1,100 @@
+package initialize
+
+import (
+	duckgoConvert "aurora/conversion/requests/duckgo"
+	"aurora/internal/duckgo"
+	"aurora/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// modelObject 返回 OpenAI /v1/models 格式的模型描述，附带注册表中的能力信息。
+func modelObject(model models.Model) gin.H {
+	return gin.H{
+		"id":             model.ID,
+		"object":         "model",
+		"created":        model.Created,
+		"owned_by":       model.OwnedBy,
+		"aliases":        model.Aliases,
+		"capabilities":   model.Capabilities,
+		"context_length": model.ContextLength,
+	}
+}
+
+// engines 返回注册表中的模型列表。
+func (h *Handler) engines(c *gin.Context) {
+	list := h.models.List()
+	data := make([]gin.H, len(list))
+	for i, model := range list {
+		data[i] = modelObject(model)
+	}
+
+	c.JSON(200, gin.H{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+// retrieveModel 处理 /v1/models/{id}，id 也可以是别名。
+func (h *Handler) retrieveModel(c *gin.Context) {
+	model, err := h.models.Resolve(c.Param("id"))
+	if err != nil {
+		writeModelNotFound(c, err)
+		return
+	}
+	c.JSON(200, modelObject(model))
+}
+
+// resolveModel 在注册表中查找模型，模型名可以带有 :search 等工具后缀。
+func (h *Handler) resolveModel(name string) (models.Model, error) {
+	base, _, err := duckgoConvert.SplitModelSuffix(name)
+	if err != nil {
+		return models.Model{}, &models.NotFoundError{Model: name}
+	}
+	return h.models.Resolve(base)
+}
+
+// writeInvalidParam 以 OpenAI 格式写出参数不被模型接受的错误。
+func writeInvalidParam(c *gin.Context, param string, err error) {
+	c.JSON(400, gin.H{"error": gin.H{
+		"message": err.Error(),
+		"type":    "invalid_request_error",
+		"param":   param,
+	}})
+}
+
+// writeModelNotFound 以 OpenAI 格式写出 model_not_found 错误；没有提供 model 时写出 400 参数错误。
+func writeModelNotFound(c *gin.Context, err error) {
+	if isMissingModel(err) {
+		writeInvalidParam(c, "model", err)
+		return
+	}
+	c.JSON(404, gin.H{"error": gin.H{
+		"message": err.Error(),
+		"type":    "invalid_request_error",
+		"param":   "model",
+		"code":    "model_not_found",
+	}})
+}
+
+// discoveredModels 将 duck.ai 上发现的模型转换为注册表条目。
+func discoveredModels(discovered []duckgo.DiscoveredModel) []models.Model {
+	list := make([]models.Model, len(discovered))
+	for i, model := range discovered {
+		list[i] = models.Model{
+			ID: model.ID,
+			Capabilities: models.Capabilities{
+				Vision:    model.Vision,
+				Tools:     model.Tools,
+				Reasoning: model.Reasoning,
+			},
+		}
+	}
+	return list
+}
+
+// isMissingModel 判断 resolveModel 的错误是否因为请求没有提供 model。
+func isMissingModel(err error) bool {
+	notFound, ok := err.(*models.NotFoundError)
+	return ok && notFound.Model == ""
+}
@@ -0,0 +1,219 @@
+package initialize
+
+import (
+	duckgoConvert "aurora/conversion/requests/duckgo"
+	"aurora/internal/duckgo"
+	"aurora/internal/models"
+	duckgotypes "aurora/typings/duckgo"
+	ollamatypes "aurora/typings/ollama"
+	"aurora/util"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ollamaVersion 是 /api/version 报告的版本号，部分客户端会据此判断接口能力。
+const ollamaVersion = "0.6.0"
+
+// ollamaChat 处理 Ollama /api/chat 请求。
+func (h *Handler) ollamaChat(c *gin.Context) {
+	var request ollamatypes.ChatRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "Request body is invalid JSON"})
+		return
+	}
+	if len(request.Messages) == 0 {
+		c.JSON(400, gin.H{"error": "messages is required"})
+		return
+	}
+	model, err := h.resolveOllamaModel(request.Model)
+	if err != nil {
+		if isMissingModel(err) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	tmpl, err := h.transcriptTemplate(c, model)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	translatedRequest, err := duckgoConvert.ConvertOllamaChatRequest(request, tmpl)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	model.Apply(&translatedRequest)
+	if err := h.prepareRequest(c.Request.Context(), model, &translatedRequest); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	h.ollamaStream(c, translatedRequest, request.Model, ollamatypes.IsStream(request.Stream), func(text string, done bool, stats ollamatypes.Stats) any {
+		resp := ollamatypes.NewChatResponse(request.Model, text, done)
+		resp.Stats = stats
+		return resp
+	})
+}
+
+// ollamaGenerate 处理 Ollama /api/generate 请求。
+func (h *Handler) ollamaGenerate(c *gin.Context) {
+	var request ollamatypes.GenerateRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "Request body is invalid JSON"})
+		return
+	}
+	model, err := h.resolveOllamaModel(request.Model)
+	if err != nil {
+		if isMissingModel(err) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	// 空 prompt 是 Ollama 客户端用来预加载模型的请求，直接返回完成即可。
+	if request.Prompt == "" {
+		resp := ollamatypes.NewGenerateResponse(request.Model, "", true)
+		resp.DoneReason = "load"
+		c.JSON(200, resp)
+		return
+	}
+	tmpl, err := h.transcriptTemplate(c, model)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	translatedRequest, err := duckgoConvert.ConvertOllamaGenerateRequest(request, tmpl)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	model.Apply(&translatedRequest)
+	if err := h.prepareRequest(c.Request.Context(), model, &translatedRequest); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	h.ollamaStream(c, translatedRequest, request.Model, ollamatypes.IsStream(request.Stream), func(text string, done bool, stats ollamatypes.Stats) any {
+		resp := ollamatypes.NewGenerateResponse(request.Model, text, done)
+		resp.Stats = stats
+		return resp
+	})
+}
+
+// resolveOllamaModel 按 Ollama 的 name:tag 约定查找模型，tag（例如 :latest）不参与匹配。
+func (h *Handler) resolveOllamaModel(name string) (models.Model, error) {
+	base, _, _ := strings.Cut(name, ":")
+	return h.models.Resolve(base)
+}
+
+// ollamaStream 发送会话并按 Ollama 协议输出：流式时逐行写出 NDJSON，
+// 否则聚合为一个 JSON 对象。build 负责构造 chat 或 generate 对应的响应结构。
+func (h *Handler) ollamaStream(c *gin.Context, translatedRequest duckgotypes.ApiRequest, model string, stream bool, build func(text string, done bool, stats ollamatypes.Stats) any) {
+	start := time.Now()
+	response, err := h.duckgoProvider.PostConversation(c.Request.Context(), translatedRequest)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to post conversation to upstream: " + err.Error()})
+		return
+	}
+	defer response.Body.Close()
+
+	if upstreamErr := duckgo.CheckResponse(c.Request.Context(), response, h.duckgoProvider); upstreamErr != nil {
+		c.JSON(upstreamErr.StatusCode, gin.H{"error": upstreamErr.Error()})
+		return
+	}
+
+	var firstToken time.Time
+	stats := func(result duckgo.StreamResult) ollamatypes.Stats {
+		end := time.Now()
+		if firstToken.IsZero() {
+			firstToken = end
+		}
+		return ollamatypes.Stats{
+			DoneReason:         result.FinishReason,
+			TotalDuration:      end.Sub(start).Nanoseconds(),
+			PromptEvalCount:    duckgo.CountPromptTokens(translatedRequest),
+			PromptEvalDuration: firstToken.Sub(start).Nanoseconds(),
+			EvalCount:          util.CountToken(result.Text),
+			EvalDuration:       end.Sub(firstToken).Nanoseconds(),
+		}
+	}
+
+	if !stream {
+		result := duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(duckgo.StreamDelta) error {
+			if firstToken.IsZero() {
+				firstToken = time.Now()
+			}
+			return nil
+		})
+		if result.Err != nil {
+			c.JSON(result.Err.StatusCode(), gin.H{"error": result.Err.Error()})
+			return
+		}
+		c.JSON(200, build(result.Text, true, stats(result)))
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	writeLine := func(v any) error {
+		if _, err := c.Writer.WriteString(ollamatypes.Line(v)); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	var writeErr error
+	result := duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(delta duckgo.StreamDelta) error {
+		if delta.Content == "" {
+			return nil
+		}
+		if firstToken.IsZero() {
+			firstToken = time.Now()
+		}
+		writeErr = writeLine(build(delta.Content, false, ollamatypes.Stats{}))
+		return writeErr
+	})
+	if writeErr != nil {
+		return
+	}
+	if result.Err != nil {
+		_ = writeLine(gin.H{"error": result.Err.Error()})
+		return
+	}
+	_ = writeLine(build("", true, stats(result)))
+}
+
+// ollamaTags 以 Ollama /api/tags 的格式列出支持的模型。
+func (h *Handler) ollamaTags(c *gin.Context) {
+	list := h.models.List()
+	models := make([]ollamatypes.ModelInfo, len(list))
+	for i, model := range list {
+		digest := sha256.Sum256([]byte(model.ID))
+		models[i] = ollamatypes.ModelInfo{
+			Name:       model.ID,
+			Model:      model.ID,
+			ModifiedAt: time.Unix(model.Created, 0).UTC().Format(time.RFC3339),
+			Digest:     hex.EncodeToString(digest[:]),
+			Details: ollamatypes.ModelDetails{
+				Format:   "remote",
+				Family:   "duckai",
+				Families: []string{"duckai"},
+			},
+		}
+	}
+	c.JSON(200, ollamatypes.TagsResponse{Models: models})
+}
+
+// ollamaVersionHandler 返回 Ollama 版本号，供客户端探测接口是否可用。
+func ollamaVersionHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"version": ollamaVersion})
+}
@@ -0,0 +1,154 @@
+package initialize
+
+import (
+	duckgoConvert "aurora/conversion/requests/duckgo"
+	"aurora/internal/duckgo"
+	"aurora/internal/files"
+	"aurora/internal/images"
+	"aurora/internal/models"
+	"aurora/logger"
+	duckgotypes "aurora/typings/duckgo"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prepareRequest 在发送到上游之前提取文件文本、按模型裁剪上下文并预处理图片，必须在 model.Apply 之后调用。
+// 请求不合法时返回 *files.Error、*duckgo.ContextLengthError 或 *images.Error，也可能返回 ctx 的错误。
+func (h *Handler) prepareRequest(ctx context.Context, model models.Model, request *duckgotypes.ApiRequest) error {
+	// 文件文本计入上下文，需要在裁剪之前提取；图片不计入，裁剪之后再下载可以少处理被丢弃的图片
+	if err := h.prepareFiles(model, request); err != nil {
+		return err
+	}
+	if err := h.fitContext(ctx, model, request); err != nil {
+		return err
+	}
+	return h.prepareImages(ctx, model, request)
+}
+
+// prepareFiles 把请求中的文件替换为提取出的文本，每个文件的长度受模型的 max_file_tokens 限制。
+func (h *Handler) prepareFiles(model models.Model, request *duckgotypes.ApiRequest) error {
+	options := h.files
+	if model.MaxFileTokens > 0 {
+		options.MaxTokens = model.MaxFileTokens
+	}
+	return eachPart(request, func(parts []any, i int) error {
+		file, ok := parts[i].(duckgotypes.PartFile)
+		if !ok {
+			return nil
+		}
+		text, err := files.Extract(file.Filename, file.Data, options)
+		if err != nil {
+			return err
+		}
+		parts[i] = duckgotypes.PartText{Type: "text", Text: text}
+		return nil
+	})
+}
+
+// fitContext 按模型的上下文长度裁剪请求，必须在 model.Apply 之后调用（预算会扣除 max_tokens）。
+// summarize 策略会额外向上游请求一次总结。只返回 *duckgo.ContextLengthError。
+func (h *Handler) fitContext(ctx context.Context, model models.Model, request *duckgotypes.ApiRequest) error {
+	strategy := model.ContextStrategy
+	if strategy == "" {
+		strategy = h.contextStrategy
+	}
+	options := duckgo.ContextOptions{
+		Limit:     model.ContextLength,
+		Strategy:  strategy,
+		KeepTurns: model.KeepLastTurns,
+	}
+	if strategy == models.ContextSummarize {
+		original := *request
+		options.Summarize = func(transcript string) (string, error) {
+			return h.complete(ctx, duckgoConvert.NewSummaryRequest(original, transcript))
+		}
+	}
+
+	result, err := duckgo.FitContext(request, options)
+	if err != nil {
+		return err
+	}
+	log := logger.Ctx(ctx)
+	if result.SummaryErr != nil {
+		log.Warnf("Failed to summarize earlier conversation, dropping it instead: %v", result.SummaryErr)
+	}
+	if result.Dropped > 0 {
+		log.Infof("Trimmed %d earlier messages to fit the %d-token context of %s (strategy %s, summarized %t)",
+			result.Dropped, model.ContextLength, model.ID, strategy, result.Summarized)
+	}
+	return nil
+}
+
+// prepareImages 把请求中的图片转换为上游可以接受的 data URL。模型不支持图片时返回 *images.Error。
+func (h *Handler) prepareImages(ctx context.Context, model models.Model, request *duckgotypes.ApiRequest) error {
+	return eachPart(request, func(parts []any, i int) error {
+		image, ok := parts[i].(duckgotypes.PartImage)
+		if !ok {
+			return nil
+		}
+		if !model.Capabilities.Vision {
+			return &images.Error{Code: "image_not_supported", Reason: fmt.Sprintf("The model `%s` does not support image inputs.", model.ID)}
+		}
+		mimeType, dataURL, err := h.images.Prepare(ctx, image.Image)
+		if err != nil {
+			return err
+		}
+		image.MimeType, image.Image = mimeType, dataURL
+		parts[i] = image
+		return nil
+	})
+}
+
+// eachPart 对请求中所有多段内容的消息逐段调用 fn，fn 可以原地替换 parts[i]。
+func eachPart(request *duckgotypes.ApiRequest, fn func(parts []any, i int) error) error {
+	for _, message := range request.Messages {
+		var parts []any
+		switch msg := message.(type) {
+		case duckgotypes.MessageUser:
+			parts, _ = msg.Content.([]any)
+		case duckgotypes.MessageAssistant:
+			parts = msg.Parts
+		}
+		for i := range parts {
+			if err := fn(parts, i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeValidationError 写出消息转换失败的错误，param 指向出错的消息字段。
+func writeValidationError(c *gin.Context, err error) {
+	param := "messages"
+	var validationErr *duckgoConvert.ValidationError
+	if errors.As(err, &validationErr) {
+		param = validationErr.Param()
+	}
+	writeInvalidParam(c, param, err)
+}
+
+// writePrepareError 以 OpenAI 格式写出 prepareRequest 返回的错误，param 为出错的请求字段。
+func writePrepareError(c *gin.Context, param string, err error) {
+	var code string
+	switch err := err.(type) {
+	case *duckgo.ContextLengthError:
+		code = "context_length_exceeded"
+	case *images.Error:
+		code = err.Code
+	case *files.Error:
+		code = "invalid_file"
+	default:
+		writeConversationError(c, err)
+		return
+	}
+	duckgo.WriteError(c, 400, gin.H{
+		"message": err.Error(),
+		"type":    "invalid_request_error",
+		"param":   param,
+		"code":    code,
+	})
+}
@@ -0,0 +1,259 @@
+package initialize
+
+import (
+	duckgoConvert "aurora/conversion/requests/duckgo"
+	"aurora/internal/duckgo"
+	"aurora/logger"
+	duckgotypes "aurora/typings/duckgo"
+	officialtypes "aurora/typings/official"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createResponse 处理 OpenAI Responses API (/v1/responses) 请求。
+// 响应默认保存在服务端，后续请求可以通过 previous_response_id 延续会话。
+func (h *Handler) createResponse(c *gin.Context) {
+	var request officialtypes.ResponsesRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": gin.H{
+			"message": "Request body is invalid JSON",
+			"type":    "invalid_request_error",
+		}})
+		return
+	}
+	bodyJSON, err := json.Marshal(request)
+	if err == nil && bodyJSON != nil {
+		logger.Ctx(c.Request.Context()).Debugf("%s", bodyJSON)
+	}
+
+	var history *officialtypes.APIRequest
+	if request.PreviousResponseID != "" {
+		entry, ok := h.responseStore.Get(request.PreviousResponseID)
+		if !ok {
+			c.JSON(404, gin.H{"error": gin.H{
+				"message": "Previous response with id '" + request.PreviousResponseID + "' not found.",
+				"type":    "invalid_request_error",
+				"param":   "previous_response_id",
+				"code":    "previous_response_not_found",
+			}})
+			return
+		}
+		history = &entry.Conversation
+	}
+
+	conversation := duckgoConvert.ResponsesToAPIRequest(request, history)
+	if len(conversation.Messages) == 0 {
+		c.JSON(400, gin.H{"error": gin.H{
+			"message": "input: at least one input item is required",
+			"type":    "invalid_request_error",
+			"param":   "input",
+		}})
+		return
+	}
+	model, err := h.resolveModel(request.Model)
+	if err != nil {
+		writeModelNotFound(c, err)
+		return
+	}
+	if err := model.ValidateReasoningEffort(request.ReasoningEffort()); err != nil {
+		writeInvalidParam(c, "reasoning.effort", err)
+		return
+	}
+	tmpl, err := h.transcriptTemplate(c, model)
+	if err != nil {
+		writeInvalidParam(c, TemplateHeader, err)
+		return
+	}
+	translatedRequest, err := duckgoConvert.ConvertResponsesRequest(request, conversation, tmpl)
+	if err != nil {
+		writeInvalidParam(c, "input", err)
+		return
+	}
+	model.Apply(&translatedRequest)
+	if err := h.prepareRequest(c.Request.Context(), model, &translatedRequest); err != nil {
+		writePrepareError(c, "input", err)
+		return
+	}
+
+	keepAlive := h.startKeepAlive(c, request.Stream)
+	defer keepAlive.Stop()
+	response, err := h.duckgoProvider.PostConversation(c.Request.Context(), translatedRequest)
+	if err != nil {
+		writeResponsesError(c, err)
+		return
+	}
+	defer response.Body.Close()
+	if upstreamErr := duckgo.CheckResponse(c.Request.Context(), response, h.duckgoProvider); upstreamErr != nil {
+		writeResponsesError(c, upstreamErr)
+		return
+	}
+
+	resp := officialtypes.NewResponse(newID("resp_"), request.Model, time.Now().Unix())
+	resp.Store = request.ShouldStore()
+	resp.Metadata = request.Metadata
+	if request.Instructions != "" {
+		resp.Instructions = &request.Instructions
+	}
+	if request.PreviousResponseID != "" {
+		resp.PreviousResponseID = &request.PreviousResponseID
+	}
+	itemID := newID("msg_")
+
+	var result duckgo.StreamResult
+	if request.Stream {
+		var ok bool
+		if result, ok = h.streamResponse(c, response, translatedRequest, resp, itemID); !ok {
+			return
+		}
+	} else {
+		result = duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(duckgo.StreamDelta) error { return nil })
+		if result.Err != nil {
+			duckgo.WriteStreamError(c, result.Err)
+			return
+		}
+	}
+
+	completeResponse(&resp, itemID, translatedRequest, result)
+	if resp.Store {
+		conversation.AddMessage("assistant", result.Text)
+		h.responseStore.Put(resp, conversation)
+	}
+	if !request.Stream {
+		c.JSON(200, resp)
+	}
+}
+
+// streamResponse 以 Responses API 的类型化 SSE 事件输出上游内容，
+// 最后发送 response.completed（或 response.incomplete）事件；上游中途出错时发送 response.failed。
+// 客户端断开或上游出错时返回 ok=false。
+func (h *Handler) streamResponse(c *gin.Context, response *http.Response, translatedRequest duckgotypes.ApiRequest, resp officialtypes.Response, itemID string) (result duckgo.StreamResult, ok bool) {
+	c.Header("Content-Type", "text/event-stream; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	sequence := 0
+	writeEvent := func(event officialtypes.ResponseStreamEvent) error {
+		event.SequenceNumber = sequence
+		sequence++
+		if _, err := c.Writer.WriteString("event: " + event.Type + "\ndata: " + event.String() + "\n\n"); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	outputIndex, contentIndex := 0, 0
+
+	inProgressItem := officialtypes.NewResponseOutputMessage(itemID, "in_progress", "")
+	emptyPart := officialtypes.NewResponseOutputText("")
+	for _, event := range []officialtypes.ResponseStreamEvent{
+		{Type: "response.created", Response: &resp},
+		{Type: "response.in_progress", Response: &resp},
+		{Type: "response.output_item.added", OutputIndex: &outputIndex, Item: &inProgressItem},
+		{Type: "response.content_part.added", OutputIndex: &outputIndex, ContentIndex: &contentIndex, ItemID: itemID, Part: &emptyPart},
+	} {
+		if writeEvent(event) != nil {
+			return result, false
+		}
+	}
+
+	var writeErr error
+	result = duckgo.ReadStream(c.Request.Context(), response.Body, translatedRequest, func(delta duckgo.StreamDelta) error {
+		if delta.Content == "" {
+			return nil
+		}
+		writeErr = writeEvent(officialtypes.ResponseStreamEvent{
+			Type:         "response.output_text.delta",
+			OutputIndex:  &outputIndex,
+			ContentIndex: &contentIndex,
+			ItemID:       itemID,
+			Delta:        &delta.Content,
+		})
+		return writeErr
+	})
+	if writeErr != nil {
+		return result, false
+	}
+	if result.Err != nil {
+		failed := resp
+		failed.Status = "failed"
+		failed.Error = &officialtypes.ResponseError{Code: "server_error", Message: result.Err.Error()}
+		_ = writeEvent(officialtypes.ResponseStreamEvent{Type: "response.failed", Response: &failed})
+		return result, false
+	}
+
+	final := resp
+	completeResponse(&final, itemID, translatedRequest, result)
+	item := final.Output[0]
+	part := item.Content[0]
+	for _, event := range []officialtypes.ResponseStreamEvent{
+		{Type: "response.output_text.done", OutputIndex: &outputIndex, ContentIndex: &contentIndex, ItemID: itemID, Text: &result.Text},
+		{Type: "response.content_part.done", OutputIndex: &outputIndex, ContentIndex: &contentIndex, ItemID: itemID, Part: &part},
+		{Type: "response.output_item.done", OutputIndex: &outputIndex, Item: &item},
+		{Type: "response." + final.Status, Response: &final},
+	} {
+		if writeEvent(event) != nil {
+			return result, false
+		}
+	}
+	return result, true
+}
+
+// writeResponsesError 写出发起上游会话失败时的错误。心跳已经写出 SSE 响应头时，
+// 改为发送 Responses API 的 error 事件，客户端按事件类型分发，不认识没有 event 行的 data。
+func writeResponsesError(c *gin.Context, err error) {
+	if !c.Writer.Written() {
+		writeConversationError(c, err)
+		return
+	}
+	event := officialtypes.ResponseStreamEvent{Type: "error", Code: "server_error", Message: err.Error()}
+	c.Writer.WriteString("event: " + event.Type + "\ndata: " + event.String() + "\n\n")
+	c.Writer.Flush()
+}
+
+// completeResponse 根据上游结果填充响应的输出、最终状态和用量。
+func completeResponse(resp *officialtypes.Response, itemID string, translatedRequest duckgotypes.ApiRequest, result duckgo.StreamResult) {
+	resp.Status = "completed"
+	if result.FinishReason == "length" {
+		resp.Status = "incomplete"
+		resp.IncompleteDetails = &officialtypes.IncompleteDetails{Reason: "max_output_tokens"}
+	}
+	resp.Output = []officialtypes.ResponseOutputItem{
+		officialtypes.NewResponseOutputMessage(itemID, resp.Status, result.Text),
+	}
+	usage := duckgo.NewUsage(translatedRequest, result.Text)
+	resp.Usage = &officialtypes.ResponseUsage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		TotalTokens:  usage.TotalTokens,
+	}
+}
+
+// getResponse 返回服务端保存的响应。
+func (h *Handler) getResponse(c *gin.Context) {
+	entry, ok := h.responseStore.Get(c.Param("id"))
+	if !ok {
+		c.JSON(404, gin.H{"error": gin.H{
+			"message": "Response with id '" + c.Param("id") + "' not found.",
+			"type":    "invalid_request_error",
+		}})
+		return
+	}
+	c.JSON(200, entry.Response)
+}
+
+// deleteResponse 删除服务端保存的响应。
+func (h *Handler) deleteResponse(c *gin.Context) {
+	id := c.Param("id")
+	if !h.responseStore.Delete(id) {
+		c.JSON(404, gin.H{"error": gin.H{
+			"message": "Response with id '" + id + "' not found.",
+			"type":    "invalid_request_error",
+		}})
+		return
+	}
+	c.JSON(200, gin.H{"id": id, "object": "response", "deleted": true})
+}
@@ -3,6 +3,7 @@ package initialize
 import (
 	"aurora/logger"
 	"aurora/middlewares"
+	"fmt"
 	"log"
 	"os"
 
@@ -21,8 +22,10 @@ func RegisterRouter() *gin.Engine {
 		log.Fatalf("Failed to initialize application handler: %v", err)
 	}
 
-	router := gin.Default()
+	router := gin.New()
+	router.Use(middlewares.RequestID, gin.LoggerWithFormatter(accessLogFormatter), gin.Recovery())
 	router.Use(middlewares.Cors)
+	router.Use(middlewares.Timeout(getDurationFromEnv("REQUEST_TIMEOUT_SECONDS", 0)))
 
 	// --- 健康检查和基本路由 ---
 	router.GET("/", func(c *gin.Context) {
@@ -41,12 +44,22 @@ func RegisterRouter() *gin.Engine {
 	registerV1ApiRoutes := func(rg *gin.RouterGroup) {
 		rg.OPTIONS("/chat/completions", optionsHandler)
 		rg.OPTIONS("/models", optionsHandler) // 修正：与 GET /v1/models 路径保持一致
+		rg.OPTIONS("/models/:id", optionsHandler)
+		rg.OPTIONS("/messages", optionsHandler)
+		rg.OPTIONS("/responses", optionsHandler)
+		rg.OPTIONS("/completions", optionsHandler)
 
 		// 使用中间件保护需要授权的路由
 		authGroup := rg.Group("").Use(middlewares.Authorization)
 		{
 			authGroup.POST("/chat/completions", handler.duckduckgo)
+			authGroup.POST("/completions", handler.completions)
 			authGroup.GET("/models", handler.engines)
+			authGroup.GET("/models/:id", handler.retrieveModel)
+			authGroup.POST("/messages", handler.anthropicMessages)
+			authGroup.POST("/responses", handler.createResponse)
+			authGroup.GET("/responses/:id", handler.getResponse)
+			authGroup.DELETE("/responses/:id", handler.deleteResponse)
 		}
 	}
 
@@ -54,13 +67,50 @@ func RegisterRouter() *gin.Engine {
 	v1Group := router.Group("/v1")
 	registerV1ApiRoutes(v1Group)
 
-	// 如果配置了 PREFIX 环境变量，则在指定前缀下再次注册所有 /v1 路由
+	// Ollama 兼容路由：NDJSON 流式输出
+	ollamaGroup := router.Group("/api")
+	ollamaGroup.GET("/version", ollamaVersionHandler)
+	ollamaAuthGroup := ollamaGroup.Group("").Use(middlewares.Authorization)
+	{
+		ollamaAuthGroup.POST("/chat", handler.ollamaChat)
+		ollamaAuthGroup.POST("/generate", handler.ollamaGenerate)
+		ollamaAuthGroup.GET("/tags", handler.ollamaTags)
+	}
+
+	// registerV1betaApiRoutes 注册 Gemini 兼容路由：/v1beta/models/{model}:generateContent 与 :streamGenerateContent
+	registerV1betaApiRoutes := func(rg *gin.RouterGroup) {
+		rg.OPTIONS("/models/:action", optionsHandler)
+
+		authGroup := rg.Group("").Use(middlewares.GeminiAuthorization)
+		{
+			authGroup.POST("/models/:action", handler.geminiGenerateContent)
+		}
+	}
+	registerV1betaApiRoutes(router.Group("/v1beta"))
+
+	// 如果配置了 PREFIX 环境变量，则在指定前缀下再次注册所有 /v1 和 /v1beta 路由
 	// 这使得 API 可以通过例如 /api/v1/... 和 /v1/... 两种方式访问
 	if prefix := os.Getenv("PREFIX"); prefix != "" {
 		prefixV1Group := router.Group(prefix + "/v1")
 		registerV1ApiRoutes(prefixV1Group)
+		registerV1betaApiRoutes(router.Group(prefix + "/v1beta"))
 		log.Printf("API routes also registered under prefix: %s", prefix)
 	}
 
 	return router
 }
+
+// accessLogFormatter 在 gin 默认的访问日志格式中加入请求 ID。
+func accessLogFormatter(param gin.LogFormatterParams) string {
+	requestID, _ := param.Keys[middlewares.RequestIDKey].(string)
+	return fmt.Sprintf("[GIN] %v | %s | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		requestID,
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		param.ErrorMessage,
+	)
+}
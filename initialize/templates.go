@@ -0,0 +1,99 @@
+package initialize
+
+import (
+	"aurora/internal/models"
+	"aurora/internal/transcript"
+	"aurora/logger"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TemplateHeader 是按请求选择对话模板的请求头，优先于模型配置的 template。
+const TemplateHeader = "X-Duck2api-Template"
+
+// transcriptTemplate 选择请求使用的对话模板：请求头 > 模型配置的 template > 默认模板，再套用模型的 system_template。
+// 只有请求头指定了不存在的模板时返回错误；模型配置的模板不存在时（例如文件被删除）记录警告并使用默认模板。
+func (h *Handler) transcriptTemplate(c *gin.Context, model models.Model) (*transcript.Template, error) {
+	name := c.GetHeader(TemplateHeader)
+	if name == "" {
+		name = model.Template
+	}
+	tmpl, ok := h.templates.Get(name)
+	if !ok {
+		if c.GetHeader(TemplateHeader) != "" {
+			return nil, fmt.Errorf("Unknown prompt template %q, available templates: %s.", name, strings.Join(h.templates.Names(), ", "))
+		}
+		logger.Ctx(c.Request.Context()).Warnf("Prompt template %q of model %s not found, using the default template", name, model.ID)
+		tmpl, _ = h.templates.Get("")
+	}
+	if model.SystemTemplate == "" {
+		return tmpl, nil
+	}
+	withSystem, err := h.systemTemplates.get(h.templates.Version(), tmpl, model.SystemTemplate)
+	if err != nil {
+		// system_template 在加载模型配置时已经校验过，这里只作为兜底
+		logger.Ctx(c.Request.Context()).Warnf("Model %s: %v", model.ID, err)
+		return tmpl, nil
+	}
+	return withSystem, nil
+}
+
+// systemTemplateCache 缓存套用了模型 system_template 的模板，避免每个请求都重新解析。
+// 模板重新加载后版本号变化，整个缓存随之失效。
+type systemTemplateCache struct {
+	mu      sync.Mutex
+	version uint64
+	entries map[systemTemplateKey]*transcript.Template
+}
+
+type systemTemplateKey struct {
+	base   *transcript.Template
+	source string
+}
+
+func (c *systemTemplateCache) get(version uint64, base *transcript.Template, source string) (*transcript.Template, error) {
+	key := systemTemplateKey{base: base, source: source}
+	c.mu.Lock()
+	if c.entries == nil || c.version != version {
+		c.entries = map[systemTemplateKey]*transcript.Template{}
+		c.version = version
+	}
+	tmpl, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := base.WithSystem(source)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.version == version {
+		c.entries[key] = tmpl
+	}
+	c.mu.Unlock()
+	return tmpl, nil
+}
+
+// newTemplateStore 从 TEMPLATES_DIR 加载对话模板，并按 TEMPLATES_RELOAD_SECONDS 定期检查文件变化。
+func newTemplateStore(registry *models.Registry) (*transcript.Store, error) {
+	store, err := transcript.NewStore(getStringFromEnv("TEMPLATES_DIR", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TEMPLATES_DIR: %w", err)
+	}
+	for _, model := range registry.List() {
+		if _, ok := store.Get(model.Template); !ok {
+			logger.Warnf("Prompt template %q of model %s not found, the default template will be used until it is added", model.Template, model.ID)
+		}
+	}
+	if interval := getNonNegativeIntFromEnv("TEMPLATES_RELOAD_SECONDS", 5); interval > 0 {
+		go store.Watch(context.Background(), time.Duration(interval)*time.Second)
+	}
+	return store, nil
+}
@@ -23,12 +23,19 @@ type browserTokenState struct {
 
 // PostConversationViaBrowser uses the browser only for token/challenge handling.
 // The actual user chat request is always sent through Go so upstream SSE can pass through.
-func (p *Provider) PostConversationViaBrowser(request duckgotypes.ApiRequest) (*http.Response, error) {
-	p.browserMutex.Lock()
+// Cancelling ctx stops waiting for the browser, aborts the token refresh and the upstream request.
+func (p *Provider) PostConversationViaBrowser(ctx context.Context, request duckgotypes.ApiRequest) (*http.Response, error) {
+	if ahead := p.browserMutex.Queued(); ahead > 0 {
+		reportStatus(ctx, Status{Stage: "queued", Position: ahead})
+	}
+	if err := p.browserMutex.Lock(ctx); err != nil {
+		return nil, err
+	}
 	defer p.browserMutex.Unlock()
 
+	reportStatus(ctx, Status{Stage: "waiting_for_upstream"})
 	if p.browserToken.isValid() {
-		resp, err := p.postConversationWithBrowserToken(request)
+		resp, err := p.postConversationWithBrowserToken(ctx, request)
 		if err == nil && resp.StatusCode != http.StatusTeapot {
 			return resp, nil
 		}
@@ -38,25 +45,28 @@ func (p *Provider) PostConversationViaBrowser(request duckgotypes.ApiRequest) (*
 		p.browserToken = cachedItem[browserTokenState]{}
 	}
 
-	if err := p.refreshBrowserToken(); err != nil {
+	reportStatus(ctx, Status{Stage: "refreshing_token"})
+	if err := p.refreshBrowserToken(ctx); err != nil {
 		return nil, err
 	}
-	return p.postConversationWithBrowserToken(request)
+	reportStatus(ctx, Status{Stage: "waiting_for_upstream"})
+	return p.postConversationWithBrowserToken(ctx, request)
 }
 
 func (p *Provider) prewarmBrowserToken() {
-	p.browserMutex.Lock()
+	ctx := context.Background()
+	p.browserMutex.Lock(ctx)
 	defer p.browserMutex.Unlock()
 	if p.browserToken.isValid() {
 		return
 	}
-	_ = p.refreshBrowserToken()
+	_ = p.refreshBrowserToken(ctx)
 }
 
-func (p *Provider) refreshBrowserToken() error {
-	challenge, err := p.getBrowserChallengeScript()
+func (p *Provider) refreshBrowserToken(ctx context.Context) error {
+	challenge, err := p.getBrowserChallengeScript(ctx)
 	if err == nil {
-		headers, buildErr := p.buildBrowserHeadersFromChallenge(challenge)
+		headers, buildErr := p.buildBrowserHeadersFromChallenge(ctx, challenge)
 		if buildErr == nil {
 			p.cacheDirectBrowserToken(headers)
 			if p.browserToken.isValid() {
@@ -68,7 +78,7 @@ func (p *Provider) refreshBrowserToken() error {
 	}
 
 	seedPrompt := getStringFromEnv("BROWSER_TOKEN_SEED_PROMPT", "ping")
-	requestHeaders, seedErr := p.runBrowserSeed(seedPrompt)
+	requestHeaders, seedErr := p.runBrowserSeed(ctx, seedPrompt)
 	if seedErr != nil {
 		if err != nil {
 			return fmt.Errorf("browser challenge execution failed: %v; seed fallback failed: %w", err, seedErr)
@@ -85,15 +95,15 @@ func (p *Provider) refreshBrowserToken() error {
 	return nil
 }
 
-func (p *Provider) postConversationWithBrowserToken(request duckgotypes.ApiRequest) (*http.Response, error) {
+func (p *Provider) postConversationWithBrowserToken(ctx context.Context, request duckgotypes.ApiRequest) (*http.Response, error) {
 	bodyJSON, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 	headers := cloneHeaders(p.browserToken.Value.headers)
-	resp, err := p.client.Request(httpclient.POST, "https://duck.ai/duckchat/v1/chat", headers, nil, bytes.NewBuffer(bodyJSON))
+	resp, err := p.client.Request(ctx, httpclient.POST, "https://duck.ai/duckchat/v1/chat", headers, nil, bytes.NewBuffer(bodyJSON))
 	if resp != nil {
-		p.updateScriptsFromHeader(resp.Header)
+		p.updateScriptsFromHeader(ctx, resp.Header)
 		p.scheduleBrowserTokenRefreshLocked()
 	}
 	return resp, err
@@ -230,7 +240,13 @@ func (p *Provider) ensureBrowserPage(ctx context.Context) error {
 	}
 
 	p.browserCtx, p.browserCancel = chromedp.NewContext(globalAllocatorCtx)
-	if err := chromedp.Run(p.browserCtx,
+	// 第一次 Run 会创建标签页，必须直接使用 browserCtx，否则标签页会随派生的 context 一起关闭
+	if err := chromedp.Run(p.browserCtx); err != nil {
+		return err
+	}
+	runCtx, cancel := p.browserRunContext(ctx, 30*time.Second)
+	defer cancel()
+	if err := chromedp.Run(runCtx,
 		network.Enable(),
 		chromedp.Navigate("https://duck.ai/"),
 		chromedp.WaitVisible("body", chromedp.ByQuery),
@@ -264,13 +280,12 @@ func (p *Provider) attachBrowserListener() {
 	})
 }
 
-func (p *Provider) buildBrowserHeadersFromChallenge(challenge string) (httpclient.AuroraHeaders, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-
+func (p *Provider) buildBrowserHeadersFromChallenge(ctx context.Context, challenge string) (httpclient.AuroraHeaders, error) {
 	if err := p.ensureBrowserPage(ctx); err != nil {
 		return nil, err
 	}
+	runCtx, cancel := p.browserRunContext(ctx, 15*time.Second)
+	defer cancel()
 
 	challengeJSON, err := json.Marshal(challenge)
 	if err != nil {
@@ -325,7 +340,7 @@ func (p *Provider) buildBrowserHeadersFromChallenge(challenge string) (httpclien
 	})()`, string(challengeJSON))
 
 	var result map[string]string
-	if err := chromedp.Run(p.browserCtx, chromedp.Evaluate(js, &result, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+	if err := chromedp.Run(runCtx, chromedp.Evaluate(js, &result, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
 		return p.WithAwaitPromise(true)
 	})); err != nil {
 		return nil, err
@@ -337,8 +352,10 @@ func (p *Provider) buildBrowserHeadersFromChallenge(challenge string) (httpclien
 	return headers, nil
 }
 
-func (p *Provider) getBrowserChallengeScript() (string, error) {
-	p.tokenMutex.Lock()
+func (p *Provider) getBrowserChallengeScript(ctx context.Context) (string, error) {
+	if err := p.tokenMutex.Lock(ctx); err != nil {
+		return "", err
+	}
 	if p.jsCode.isValid() && p.jsCode.Value != "" {
 		challenge := p.jsCode.Value
 		p.tokenMutex.Unlock()
@@ -346,12 +363,14 @@ func (p *Provider) getBrowserChallengeScript() (string, error) {
 	}
 	p.tokenMutex.Unlock()
 
-	challenge, err := p.getScripts(true)
+	challenge, err := p.getScripts(ctx, true)
 	if err != nil {
 		return "", err
 	}
 
-	p.tokenMutex.Lock()
+	if err := p.tokenMutex.Lock(ctx); err != nil {
+		return "", err
+	}
 	p.jsCode = cachedItem[string]{
 		Value:    challenge,
 		ExpireAt: time.Now().Add(p.scriptsCacheDuration),
@@ -367,20 +386,22 @@ func (p *Provider) scheduleBrowserTokenRefreshLocked() {
 	p.browserRefreshInFlight = true
 
 	go func() {
+		// 后台刷新不属于任何客户端请求，不随请求取消
+		ctx := context.Background()
 		defer func() {
-			p.browserMutex.Lock()
+			p.browserMutex.Lock(ctx)
 			p.browserRefreshInFlight = false
 			p.browserMutex.Unlock()
 		}()
 
-		p.browserMutex.Lock()
+		p.browserMutex.Lock(ctx)
 		defer p.browserMutex.Unlock()
 
-		challenge, err := p.getBrowserChallengeScript()
+		challenge, err := p.getBrowserChallengeScript(ctx)
 		if err != nil {
 			return
 		}
-		headers, err := p.buildBrowserHeadersFromChallenge(challenge)
+		headers, err := p.buildBrowserHeadersFromChallenge(ctx, challenge)
 		if err != nil {
 			return
 		}
@@ -388,15 +409,13 @@ func (p *Provider) scheduleBrowserTokenRefreshLocked() {
 	}()
 }
 
-func (p *Provider) runBrowserSeed(prompt string) (network.Headers, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
-
+func (p *Provider) runBrowserSeed(ctx context.Context, prompt string) (network.Headers, error) {
 	if err := p.ensureBrowserPage(ctx); err != nil {
 		return nil, err
 	}
 
-	runCtx := p.browserCtx
+	runCtx, cancel := p.browserRunContext(ctx, 20*time.Second)
+	defer cancel()
 	requestHeaders := make(chan network.Headers, 1)
 	p.browserRequestHeadersCh = requestHeaders
 	defer func() { p.browserRequestHeadersCh = nil }()
@@ -413,6 +432,8 @@ func (p *Provider) runBrowserSeed(prompt string) (network.Headers, error) {
 		return headers, nil
 	case <-time.After(10 * time.Second):
 		return nil, errors.New("timed out waiting for browser seed request")
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
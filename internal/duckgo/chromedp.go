@@ -8,7 +8,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
 	"strings"
@@ -52,7 +51,7 @@ func initChromedp() (context.CancelFunc, error) {
 }
 
 // getSandboxURL 通过执行一段初始化 JS 来获取沙箱环境的 URL 和一个可能的初始 token。
-func (p *Provider) getSandboxURL() (string, string, error) {
+func (p *Provider) getSandboxURL(ctx context.Context) (string, string, error) {
 	if p.sandboxURL.isValid() {
 		return p.sandboxURL.Value, "", nil
 	}
@@ -78,14 +77,14 @@ func (p *Provider) getSandboxURL() (string, string, error) {
 		})();
 	`
 
-	logger.Infof("getting sanboxURL from chromedp")
+	logger.Ctx(ctx).Infof("getting sanboxURL from chromedp")
 	initialURL := "https://duck.ai/"
 	var result struct {
 		SandboxURL      string         `json:"sandboxUrl"`
 		InitialJSResult map[string]any `json:"initialJsResult"`
 	}
 
-	err := executeJS(initialURL, initJS, &result)
+	err := executeJS(ctx, initialURL, initJS, &result)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to execute initial JS for sandbox: %w", err)
 	}
@@ -96,7 +95,7 @@ func (p *Provider) getSandboxURL() (string, string, error) {
 
 	initialToken, err := encodeToToken(result.InitialJSResult)
 	if err != nil {
-		log.Printf("Could not generate initial token from sandbox result: %v", err)
+		logger.Ctx(ctx).Warnf("Could not generate initial token from sandbox result: %v", err)
 		return result.SandboxURL, "", nil
 	}
 
@@ -104,9 +103,9 @@ func (p *Provider) getSandboxURL() (string, string, error) {
 }
 
 // generateTokenFromJS 在给定的沙箱环境中执行 JS 代码以生成 token。
-func (p *Provider) generateTokenFromJS(jsCode, sandboxURL string) (string, error) {
+func (p *Provider) generateTokenFromJS(ctx context.Context, jsCode, sandboxURL string) (string, error) {
 	var rawJsResult map[string]any
-	err := executeJS(sandboxURL, jsCode, &rawJsResult)
+	err := executeJS(ctx, sandboxURL, jsCode, &rawJsResult)
 	if err != nil {
 		return "", err
 	}
@@ -119,7 +118,8 @@ func (p *Provider) generateTokenFromJS(jsCode, sandboxURL string) (string, error
 }
 
 // executeJS 是一个通用的辅助函数，用于在新的 ChromeDP 标签页中导航到指定 URL 并执行 JS。
-func executeJS(url, jsCode string, result any) error {
+// ctx 结束时执行被中止，标签页随之关闭。
+func executeJS(ctx context.Context, url, jsCode string, result any) error {
 	if globalAllocatorCtx == nil {
 		return errors.New("chromedp allocator not initialized")
 	}
@@ -129,6 +129,8 @@ func executeJS(url, jsCode string, result any) error {
 
 	execCtx, execCancel := context.WithTimeout(tabCtx, 30*time.Second)
 	defer execCancel()
+	stop := context.AfterFunc(ctx, execCancel)
+	defer stop()
 
 	err := chromedp.Run(execCtx,
 		chromedp.Navigate(url),
@@ -0,0 +1,144 @@
+package duckgo
+
+import (
+	officialtypes "aurora/typings/official"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// Citation 是上游搜索工具返回的一条来源。
+type Citation struct {
+	URL   string
+	Title string
+}
+
+// citationCollector 从上游事件中收集搜索来源，按 URL 去重并保持出现顺序。
+// duck.ai 搜索结果的字段名并不固定，这里递归查找带有 http(s) 链接的对象，
+// 只跳过模型输出的正文字段。
+type citationCollector struct {
+	seen      map[string]bool
+	citations []Citation
+}
+
+func (c *citationCollector) add(data string) {
+	if !strings.Contains(data, "http") {
+		return
+	}
+	value, err := decodeOrdered(json.NewDecoder(strings.NewReader(data)))
+	event, ok := value.(orderedObject)
+	if err != nil || !ok {
+		return
+	}
+	for _, field := range event {
+		if field.key != "message" && field.key != "reasoning" {
+			c.walk(field.value)
+		}
+	}
+}
+
+func (c *citationCollector) walk(value any) {
+	switch v := value.(type) {
+	case orderedObject:
+		if url := v.firstString("url", "link", "href"); strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+			if c.seen == nil {
+				c.seen = make(map[string]bool)
+			}
+			if !c.seen[url] {
+				c.seen[url] = true
+				c.citations = append(c.citations, Citation{URL: url, Title: v.firstString("title", "name", "source")})
+			}
+			return
+		}
+		for _, field := range v {
+			c.walk(field.value)
+		}
+	case []any:
+		for _, child := range v {
+			c.walk(child)
+		}
+	}
+}
+
+// orderedObject 是按源文本顺序保存字段的 JSON 对象，
+// 用于让同一事件中不同字段下的来源也按出现顺序收集。
+type orderedObject []orderedField
+
+type orderedField struct {
+	key   string
+	value any
+}
+
+func (o orderedObject) firstString(keys ...string) string {
+	for _, key := range keys {
+		for _, field := range o {
+			if s, ok := field.value.(string); ok && field.key == key && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// decodeOrdered 读取一个 JSON 值，对象解码为 orderedObject，其余与 encoding/json 的默认类型一致。
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := orderedObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, orderedField{key: key.(string), value: value})
+		}
+		_, err = dec.Token()
+		return object, err
+	case json.Delim('['):
+		array := []any{}
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = dec.Token()
+		return array, err
+	}
+	return token, nil
+}
+
+// Annotations 将来源转换为 OpenAI 的 url_citation 注释。
+// 上游不提供引用在正文中的位置；正文中出现了来源 URL 时以它的位置（按字符计）作为范围，
+// 否则不设置 start_index / end_index。
+func (r StreamResult) Annotations() []officialtypes.Annotation {
+	if len(r.Citations) == 0 {
+		return nil
+	}
+	annotations := make([]officialtypes.Annotation, len(r.Citations))
+	for i, citation := range r.Citations {
+		annotations[i] = officialtypes.Annotation{
+			Type: "url_citation",
+			URLCitation: officialtypes.URLCitation{
+				URL:   citation.URL,
+				Title: citation.Title,
+			},
+		}
+		if offset := strings.Index(r.Text, citation.URL); offset >= 0 {
+			start := utf8.RuneCountInString(r.Text[:offset])
+			end := start + utf8.RuneCountInString(citation.URL)
+			annotations[i].URLCitation.StartIndex = &start
+			annotations[i].URLCitation.EndIndex = &end
+		}
+	}
+	return annotations
+}
@@ -0,0 +1,63 @@
+package duckgo
+
+import (
+	duckgotypes "aurora/typings/duckgo"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadStreamCollectsCitations(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"action":"tool_result","results":[{"title":"Go","url":"https://go.dev"},{"name":"Docs","link":"https://pkg.go.dev"}]}`,
+		`data: {"message":"see https://example.com","model":"gpt-4o-mini"}`,
+		`data: {"action":"tool_result","results":[{"title":"Go again","url":"https://go.dev"}]}`,
+		`data: [DONE]`,
+		"",
+	}, "\n")
+	result := ReadStream(context.Background(), strings.NewReader(body), duckgotypes.ApiRequest{Model: "gpt-4o-mini"}, func(StreamDelta) error { return nil })
+	want := []Citation{{URL: "https://go.dev", Title: "Go"}, {URL: "https://pkg.go.dev", Title: "Docs"}}
+	if len(result.Citations) != len(want) {
+		t.Fatalf("unexpected citations %+v", result.Citations)
+	}
+	for i := range want {
+		if result.Citations[i] != want[i] {
+			t.Fatalf("citation %d: got %+v, want %+v", i, result.Citations[i], want[i])
+		}
+	}
+	annotations := result.Annotations()
+	if annotations[0].Type != "url_citation" || annotations[0].URLCitation.StartIndex != nil || annotations[0].URLCitation.EndIndex != nil {
+		t.Fatalf("unexpected annotation %+v", annotations[0])
+	}
+}
+
+func TestAnnotationsSpanCitedURL(t *testing.T) {
+	result := StreamResult{
+		Text:      "详见 https://go.dev 和其他资料",
+		Citations: []Citation{{URL: "https://go.dev", Title: "Go"}},
+	}
+	citation := result.Annotations()[0].URLCitation
+	if citation.StartIndex == nil || citation.EndIndex == nil || *citation.StartIndex != 3 || *citation.EndIndex != 17 {
+		t.Fatalf("unexpected span %+v", citation)
+	}
+}
+
+func TestReadStreamKeepsCitationSourceOrder(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"action":"tool_result","web":[{"title":"W","url":"https://w.example"}],"news":[{"title":"N","url":"https://n.example"}],"a":{"url":"https://a.example"}}`,
+		`data: [DONE]`,
+		"",
+	}, "\n")
+	for i := 0; i < 20; i++ {
+		result := ReadStream(context.Background(), strings.NewReader(body), duckgotypes.ApiRequest{Model: "gpt-4o-mini"}, func(StreamDelta) error { return nil })
+		want := []string{"https://w.example", "https://n.example", "https://a.example"}
+		if len(result.Citations) != len(want) {
+			t.Fatalf("unexpected citations %+v", result.Citations)
+		}
+		for j := range want {
+			if result.Citations[j].URL != want[j] {
+				t.Fatalf("citation %d: got %s, want %s", j, result.Citations[j].URL, want[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package duckgo
+
+import (
+	"aurora/logger"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// DiscoveredModel 是从 duck.ai status 接口中发现的一个模型。
+// 能力字段只在上游明确声明时为 true。
+type DiscoveredModel struct {
+	ID        string `json:"id"`
+	Vision    bool   `json:"vision"`
+	Tools     bool   `json:"tools"` // 支持 duck.ai 内置工具（搜索、天气等）
+	Reasoning bool   `json:"reasoning"`
+}
+
+// discoverModelsJS 在 duck.ai 页面中请求 status 接口，原样返回其中的 models 字段。
+// 在页面中请求是为了带上浏览器的 cookie 和挑战结果。
+const discoverModelsJS = `(async () => {
+	const res = await fetch('/duckchat/v1/status', { credentials: 'include', cache: 'no-store' });
+	const data = await res.json();
+	return Array.isArray(data.models) ? data.models : null;
+})()`
+
+// DiscoverModels 从 duck.ai 的 status 接口中读取当前可用的模型列表。
+// 接口没有返回模型列表时返回错误，不会猜测页面结构。
+func (p *Provider) DiscoverModels(ctx context.Context) ([]DiscoveredModel, error) {
+	if err := p.browserMutex.Lock(ctx); err != nil {
+		return nil, err
+	}
+	defer p.browserMutex.Unlock()
+
+	if err := p.ensureBrowserPage(ctx); err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := p.browserRunContext(ctx, 15*time.Second)
+	defer cancel()
+	var raw []map[string]any
+	if err := chromedp.Run(runCtx, chromedp.Evaluate(discoverModelsJS, &raw, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	})); err != nil {
+		return nil, err
+	}
+	discovered := parseDiscoveredModels(raw)
+	if len(discovered) == 0 {
+		return nil, errors.New("duck.ai status did not list any models")
+	}
+	return discovered, nil
+}
+
+// parseDiscoveredModels 把 status 接口中的模型条目转换为 DiscoveredModel，按 ID 去重并排序。
+// 能力可以是条目上的布尔字段，也可以列在 capabilities 数组中。
+func parseDiscoveredModels(raw []map[string]any) []DiscoveredModel {
+	seen := make(map[string]bool, len(raw))
+	var discovered []DiscoveredModel
+	for _, entry := range raw {
+		id, _ := entry["id"].(string)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		discovered = append(discovered, DiscoveredModel{
+			ID:        id,
+			Vision:    hasCapability(entry, "vision"),
+			Tools:     hasCapability(entry, "tools"),
+			Reasoning: hasCapability(entry, "reasoning"),
+		})
+	}
+	sort.Slice(discovered, func(i, j int) bool { return discovered[i].ID < discovered[j].ID })
+	return discovered
+}
+
+func hasCapability(entry map[string]any, name string) bool {
+	if enabled, ok := entry[name].(bool); ok {
+		return enabled
+	}
+	capabilities, _ := entry["capabilities"].([]any)
+	for _, capability := range capabilities {
+		if s, ok := capability.(string); ok && strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchModels 每隔 interval 发现一次模型并回调 onUpdate，直到 ctx 结束。
+// 与上一次结果相比消失或新增的模型会记录到日志中；发现失败时保留上一次的结果。
+func (p *Provider) WatchModels(ctx context.Context, interval time.Duration, onUpdate func([]DiscoveredModel)) {
+	var previous map[string]bool
+	for {
+		discovered, err := p.DiscoverModels(ctx)
+		if err != nil {
+			logger.Warnf("Model discovery failed: %v", err)
+		} else {
+			current := make(map[string]bool, len(discovered))
+			for _, model := range discovered {
+				current[model.ID] = true
+			}
+			if previous != nil {
+				if gone := missingModels(previous, current); len(gone) > 0 {
+					logger.Warnf("Models no longer available upstream: %s", strings.Join(gone, ", "))
+				}
+				if added := missingModels(current, previous); len(added) > 0 {
+					logger.Infof("New models available upstream: %s", strings.Join(added, ", "))
+				}
+			} else {
+				logger.Infof("Discovered %d upstream models", len(discovered))
+			}
+			previous = current
+			onUpdate(discovered)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// missingModels 返回在 from 中但不在 to 中的模型 ID，按字母排序。
+func missingModels(from, to map[string]bool) []string {
+	var missing []string
+	for id := range from {
+		if !to[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,25 @@
+package duckgo
+
+import "testing"
+
+func TestParseDiscoveredModels(t *testing.T) {
+	raw := []map[string]any{
+		{"id": "gpt-5-mini", "reasoning": true, "capabilities": []any{"Vision"}},
+		{"id": "gpt-4o-mini", "tools": false},
+		{"id": "gpt-4o-mini", "tools": true},
+		{"name": "no id"},
+	}
+	discovered := parseDiscoveredModels(raw)
+	want := []DiscoveredModel{
+		{ID: "gpt-4o-mini"},
+		{ID: "gpt-5-mini", Vision: true, Reasoning: true},
+	}
+	if len(discovered) != len(want) {
+		t.Fatalf("unexpected models %+v", discovered)
+	}
+	for i := range want {
+		if discovered[i] != want[i] {
+			t.Fatalf("model %d: got %+v, want %+v", i, discovered[i], want[i])
+		}
+	}
+}
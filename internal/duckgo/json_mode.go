@@ -0,0 +1,91 @@
+package duckgo
+
+import (
+	"aurora/internal/jsonschema"
+	"aurora/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// JSONOptions 启用 response_format 的 JSON 输出校验。
+type JSONOptions struct {
+	Schema     any // json_schema 模式下的 schema；为 nil 时只要求输出 JSON 对象
+	MaxRetries int // 校验失败后最多重新请求的次数
+	// Retry 在校验失败时调用，返回上游重新生成的完整输出。
+	Retry func(previous string, validationErr error) (string, error)
+}
+
+var jsonCodeFencePattern = regexp.MustCompile("(?s)```(?:json|JSON)?\\s*(.*?)\\s*```")
+
+// enforceJSON 校验输出是否为满足要求的 JSON，不满足时通过 Retry 重新请求，
+// 最多 MaxRetries 次。成功时 result.Text 被替换为提取出的 JSON；
+// 重试耗尽后保留最后一次的输出。
+func enforceJSON(ctx context.Context, result *StreamResult, options *JSONOptions) {
+	text := result.Text
+	for attempt := 0; ; attempt++ {
+		extracted, err := checkJSON(text, options.Schema)
+		if err == nil {
+			result.Text = extracted
+			return
+		}
+		// 因长度限制被截断的输出重试也无济于事
+		if result.FinishReason == "length" || attempt >= options.MaxRetries || options.Retry == nil {
+			logger.Ctx(ctx).Warnf("Returning output that failed JSON validation after %d retries: %v", attempt, err)
+			result.Text = text
+			return
+		}
+		logger.Ctx(ctx).Warnf("Output failed JSON validation, retrying (%d/%d): %v", attempt+1, options.MaxRetries, err)
+		retried, retryErr := options.Retry(text, err)
+		if retryErr != nil {
+			logger.Ctx(ctx).Warnf("JSON validation retry failed: %v", retryErr)
+			result.Text = text
+			return
+		}
+		text = retried
+	}
+}
+
+// checkJSON 从模型输出中提取 JSON 并按 schema 校验，返回提取出的 JSON 文本。
+func checkJSON(text string, schema any) (string, error) {
+	extracted := extractJSON(text)
+	var value any
+	if err := json.Unmarshal([]byte(extracted), &value); err != nil {
+		return "", errors.New("output is not valid JSON: " + err.Error())
+	}
+	if schema == nil {
+		if _, ok := value.(map[string]any); !ok {
+			return "", errors.New("output must be a JSON object")
+		}
+		return extracted, nil
+	}
+	if err := jsonschema.Validate(schema, value); err != nil {
+		return "", errors.New("output does not match the JSON schema: " + err.Error())
+	}
+	return extracted, nil
+}
+
+// extractJSON 去掉模型常见的 markdown 代码块和前后说明文字，返回最可能的 JSON 片段。
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if json.Valid([]byte(text)) {
+		return text
+	}
+	if m := jsonCodeFencePattern.FindStringSubmatch(text); m != nil && json.Valid([]byte(m[1])) {
+		return m[1]
+	}
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return text
+	}
+	closing := "}"
+	if text[start] == '[' {
+		closing = "]"
+	}
+	if end := strings.LastIndex(text, closing); end > start {
+		return text[start : end+1]
+	}
+	return text
+}
@@ -0,0 +1,40 @@
+package duckgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEnforceJSONExtractsFencedObject(t *testing.T) {
+	result := StreamResult{Text: "Here you go:\n```json\n{\"ok\": true}\n```"}
+	enforceJSON(context.Background(), &result, &JSONOptions{})
+	if result.Text != `{"ok": true}` {
+		t.Fatalf("unexpected text %q", result.Text)
+	}
+}
+
+func TestEnforceJSONRetriesUntilSchemaMatches(t *testing.T) {
+	schema := map[string]any{
+		"type":     "object",
+		"required": []any{"answer"},
+	}
+	replies := []string{`{"wrong": 1}`, `{"answer": 42}`}
+	calls := 0
+	result := StreamResult{Text: "not json"}
+	enforceJSON(context.Background(), &result, &JSONOptions{
+		Schema:     schema,
+		MaxRetries: 3,
+		Retry: func(previous string, validationErr error) (string, error) {
+			if validationErr == nil {
+				return "", errors.New("missing validation error")
+			}
+			reply := replies[calls]
+			calls++
+			return reply, nil
+		},
+	})
+	if calls != 2 || result.Text != `{"answer": 42}` {
+		t.Fatalf("calls = %d, text = %q", calls, result.Text)
+	}
+}
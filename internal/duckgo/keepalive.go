@@ -0,0 +1,121 @@
+package duckgo
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Status 是等待上游期间的进度，通过可选的 SSE 扩展事件发送给客户端。
+type Status struct {
+	Stage    string `json:"stage"`              // queued / refreshing_token / waiting_for_upstream
+	Position int    `json:"position,omitempty"` // 排队时前面还有多少个请求
+}
+
+type statusReporterKey struct{}
+
+// WithStatusReporter 返回携带进度回调的 context，Provider 在排队、刷新 token 等阶段会调用它。
+func WithStatusReporter(ctx context.Context, report func(Status)) context.Context {
+	return context.WithValue(ctx, statusReporterKey{}, report)
+}
+
+func reportStatus(ctx context.Context, status Status) {
+	if report, ok := ctx.Value(statusReporterKey{}).(func(Status)); ok {
+		report(status)
+	}
+}
+
+// KeepAlive 在流式请求收到第一段内容之前，定期向客户端发送 SSE 注释行，
+// 避免首次请求等待浏览器和 token 刷新时被反向代理或客户端的空闲超时断开。
+// nil 的 *KeepAlive 可以安全地调用 Stop。
+type KeepAlive struct {
+	writer  gin.ResponseWriter // 原始 writer，心跳不经过 keepAliveWriter
+	mu      sync.Mutex
+	stopped bool
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// StartKeepAlive 立即写出 SSE 响应头，并每隔 interval 发送一次心跳，直到第一次写出响应内容、
+// 调用 Stop 或请求结束。statusEvents 为 true 时还会以 duck2api.status 事件报告 Provider 的进度。
+// 响应头写出后状态码无法再修改，之后的错误需要以 SSE 事件发送（见 WriteError）。
+func StartKeepAlive(c *gin.Context, interval time.Duration, statusEvents bool) *KeepAlive {
+	k := &KeepAlive{writer: c.Writer, stop: make(chan struct{})}
+	c.Header("Content-Type", "text/event-stream; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	c.Writer = &keepAliveWriter{ResponseWriter: c.Writer, keepAlive: k}
+	if statusEvents {
+		c.Request = c.Request.WithContext(WithStatusReporter(c.Request.Context(), k.report))
+	}
+
+	done := c.Request.Context().Done()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-k.stop:
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				k.write(": keep-alive\n\n")
+			}
+		}
+	}()
+	return k
+}
+
+// Stop 停止发送心跳。返回后不会再有心跳写入连接。
+func (k *KeepAlive) Stop() {
+	if k == nil {
+		return
+	}
+	k.once.Do(func() {
+		k.mu.Lock()
+		k.stopped = true
+		k.mu.Unlock()
+		close(k.stop)
+	})
+}
+
+func (k *KeepAlive) write(payload string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.stopped {
+		return
+	}
+	if _, err := k.writer.WriteString(payload); err == nil {
+		k.writer.Flush()
+	}
+}
+
+func (k *KeepAlive) report(status Status) {
+	data, _ := json.Marshal(status)
+	k.write("event: duck2api.status\ndata: " + string(data) + "\n\n")
+}
+
+// keepAliveWriter 在处理器第一次写出内容前停止心跳，保证两者不会同时写入连接。
+type keepAliveWriter struct {
+	gin.ResponseWriter
+	keepAlive *KeepAlive
+}
+
+func (w *keepAliveWriter) Write(data []byte) (int, error) {
+	w.keepAlive.Stop()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *keepAliveWriter) WriteString(s string) (int, error) {
+	w.keepAlive.Stop()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,39 @@
+package duckgo
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestKeepAliveStopsOnFirstWrite(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+
+	keepAlive := StartKeepAlive(c, 5*time.Millisecond, true)
+	defer keepAlive.Stop()
+	reportStatus(c.Request.Context(), Status{Stage: "refreshing_token"})
+	time.Sleep(30 * time.Millisecond)
+	c.Writer.WriteString("data: first\n\n")
+	time.Sleep(20 * time.Millisecond)
+
+	body := recorder.Body.String()
+	if recorder.Header().Get("Content-Type") != "text/event-stream; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", recorder.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(body, `event: duck2api.status`+"\n"+`data: {"stage":"refreshing_token"}`) {
+		t.Fatalf("missing status event in %q", body)
+	}
+	heartbeat := strings.Index(body, ": keep-alive\n\n")
+	first := strings.Index(body, "data: first")
+	if heartbeat < 0 || heartbeat > first {
+		t.Fatalf("expected heartbeat before content, got %q", body)
+	}
+	if strings.Contains(body[first:], ": keep-alive") {
+		t.Fatalf("heartbeat sent after content: %q", body)
+	}
+}
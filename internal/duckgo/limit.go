@@ -0,0 +1,46 @@
+package duckgo
+
+import (
+	"aurora/util"
+	"strings"
+)
+
+// tokenLimiter 在网关侧执行 max_tokens：累计已输出的 token 数，
+// 超出上限时把当前片段截断到上限处并报告已达到上限。
+type tokenLimiter struct {
+	max     int
+	tokens  int
+	emitted strings.Builder
+}
+
+func newTokenLimiter(max int) *tokenLimiter {
+	return &tokenLimiter{max: max}
+}
+
+// push 返回 text 中仍可输出的部分；exceeded 为 true 时调用方应停止读取。
+func (l *tokenLimiter) push(text string) (output string, exceeded bool) {
+	if l.max <= 0 || text == "" {
+		return text, false
+	}
+	// 逐段累加是整体 token 数的近似上界，只有接近上限时才对全文精确计数
+	if estimated := l.tokens + util.CountToken(text); estimated <= l.max {
+		l.tokens = estimated
+		l.emitted.WriteString(text)
+		return text, false
+	}
+	emitted := l.emitted.String()
+	candidate := emitted + text
+	if exact := util.CountToken(candidate); exact <= l.max {
+		l.tokens = exact
+		l.emitted.WriteString(text)
+		return text, false
+	}
+	truncated := util.TruncateToTokens(candidate, l.max)
+	if len(truncated) <= len(emitted) || !strings.HasPrefix(truncated, emitted) {
+		return "", true
+	}
+	output = truncated[len(emitted):]
+	l.emitted.WriteString(output)
+	l.tokens = l.max
+	return output, true
+}
@@ -0,0 +1,54 @@
+package duckgo
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ctxMutex 是等待时可以被 context 取消的互斥锁，零值可用。
+// 客户端断开后，排队等待浏览器的请求可以立即放弃，而不是一直阻塞到前面的操作完成。
+type ctxMutex struct {
+	once   sync.Once
+	ch     chan struct{}
+	queued atomic.Int32 // 持有锁和正在等待的数量
+}
+
+func (m *ctxMutex) init() {
+	m.once.Do(func() { m.ch = make(chan struct{}, 1) })
+}
+
+// Lock 获取锁，ctx 先结束时返回 ctx.Err()。
+func (m *ctxMutex) Lock(ctx context.Context) error {
+	m.init()
+	m.queued.Add(1)
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		m.queued.Add(-1)
+		return ctx.Err()
+	}
+}
+
+func (m *ctxMutex) Unlock() {
+	m.queued.Add(-1)
+	<-m.ch
+}
+
+// Queued 返回持有锁和正在等待的数量，即新请求前面的排队数。
+func (m *ctxMutex) Queued() int {
+	return int(m.queued.Load())
+}
+
+// browserRunContext 返回在浏览器页面上执行操作用的 context。
+// 超时或 ctx 结束（例如客户端断开）时操作被中止，页面本身保留给后续请求使用。
+func (p *Provider) browserRunContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	runCtx, cancel := context.WithTimeout(p.browserCtx, timeout)
+	stop := context.AfterFunc(ctx, cancel)
+	return runCtx, func() {
+		stop()
+		cancel()
+	}
+}
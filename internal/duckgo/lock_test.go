@@ -0,0 +1,41 @@
+package duckgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCtxMutexLockCanceled(t *testing.T) {
+	var m ctxMutex
+	if err := m.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while locked, got %v", err)
+	}
+
+	m.Unlock()
+	if err := m.Lock(context.Background()); err != nil {
+		t.Fatalf("lock after unlock failed: %v", err)
+	}
+	m.Unlock()
+}
+
+func TestGetTokenStopsWaitingWhenCanceled(t *testing.T) {
+	var p Provider
+	if err := p.tokenMutex.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.tokenMutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetToken(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while a refresh holds the lock, got %v", err)
+	}
+}
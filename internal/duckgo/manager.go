@@ -15,7 +15,6 @@ import (
 	"net/http"
 	"os"
 	"strconv"
-	"sync"
 	"time"
 
 	"github.com/chromedp/cdproto/network"
@@ -47,8 +46,8 @@ type Provider struct {
 	vqdToken                cachedItem[string] // 缓存 vqd-hash token
 	jsCode                  cachedItem[string] // 缓存从 header 获取的 JS 代码
 	sandboxURL              cachedItem[string] // 缓存用于执行 JS 的沙箱环境 URL
-	tokenMutex              sync.Mutex         // 用于保护 token 刷新过程的互斥锁
-	browserMutex            sync.Mutex         // Duck.ai 页面自动化串行锁
+	tokenMutex              ctxMutex           // 用于保护 token 刷新过程的互斥锁，等待时随请求取消
+	browserMutex            ctxMutex           // Duck.ai 页面自动化串行锁
 	browserToken            cachedItem[browserTokenState]
 	chromeCancel            context.CancelFunc // 用于在程序结束时关闭 ChromeDP 上下文
 	browserCtx              context.Context
@@ -108,15 +107,17 @@ func getStringFromEnv(key, defaultValue string) string {
 }
 
 // InvalidateCache 在 API 返回错误时清空所有缓存。
-// 这是线程安全的。
-func (p *Provider) InvalidateCache() {
-	p.tokenMutex.Lock()
+// 这是线程安全的。等待正在进行的刷新时 ctx 结束则放弃清空，刷新完成后缓存本来就是新的。
+func (p *Provider) InvalidateCache(ctx context.Context) {
+	if err := p.tokenMutex.Lock(ctx); err != nil {
+		return
+	}
 	defer p.tokenMutex.Unlock()
 
 	p.vqdToken = cachedItem[string]{}
 	p.jsCode = cachedItem[string]{}
 	p.sandboxURL = cachedItem[string]{}
-	logger.Warnf("All caches have been invalidated due to an API error.")
+	logger.Ctx(ctx).Warnf("All caches have been invalidated due to an API error.")
 }
 
 // Close 优雅地关闭 Provider 所持有的资源，例如 ChromeDP 连接。
@@ -129,10 +130,12 @@ func (p *Provider) Close() {
 }
 
 // GetToken 获取一个有效的 vqd-hash token。
-// 如果缓存的 token 无效或已过期，它会自动执行刷新流程。
+// 如果缓存的 token 无效或已过期，它会自动执行刷新流程；ctx 结束时刷新被中止。
 // 这个方法是线程安全的。
-func (p *Provider) GetToken() (string, error) {
-	p.tokenMutex.Lock()
+func (p *Provider) GetToken(ctx context.Context) (string, error) {
+	if err := p.tokenMutex.Lock(ctx); err != nil {
+		return "", err
+	}
 	defer p.tokenMutex.Unlock()
 
 	if p.vqdToken.isValid() {
@@ -141,15 +144,16 @@ func (p *Provider) GetToken() (string, error) {
 	}
 
 	// 如果 token 无效，则启动完整的刷新流程
-	logger.Infof("Refreshing token...")
-	return p.refreshToken()
+	logger.Ctx(ctx).Infof("Refreshing token...")
+	reportStatus(ctx, Status{Stage: "refreshing_token"})
+	return p.refreshToken(ctx)
 }
 
 // refreshToken 执行获取新 token 的完整流程。
 // 注意：此方法不是线程安全的，应由 GetToken 等公共方法在锁的保护下调用。
-func (p *Provider) refreshToken() (string, error) {
+func (p *Provider) refreshToken(ctx context.Context) (string, error) {
 	// 1. 获取执行 JS 所需的沙箱环境
-	sandboxURL, _, err := p.getSandboxURL()
+	sandboxURL, _, err := p.getSandboxURL(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get sandbox url: %w", err)
 	}
@@ -159,7 +163,7 @@ func (p *Provider) refreshToken() (string, error) {
 	// 2. 获取 JS
 	jsCode := p.jsCode.Value
 	if !p.jsCode.isValid() {
-		jsCode, err = p.getScripts(true)
+		jsCode, err = p.getScripts(ctx, true)
 		if err != nil {
 			return "", fmt.Errorf("failed to get scripts for token generation: %w", err)
 		}
@@ -168,13 +172,13 @@ func (p *Provider) refreshToken() (string, error) {
 	}
 
 	// 3. 生成 Token
-	token, err := p.generateTokenFromJS(jsCode, sandboxURL)
+	token, err := p.generateTokenFromJS(ctx, jsCode, sandboxURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to execute obfuscated js: %w", err)
 	}
 
 	p.cacheToken(token)
-	logger.Debugf("Successfully refreshed VQD token.")
+	logger.Ctx(ctx).Debugf("Successfully refreshed VQD token.")
 	return token, nil
 }
 
@@ -189,7 +193,7 @@ func (p *Provider) cacheToken(token string) {
 
 // getScripts 从 DuckDuckGo status 接口获取用于生成 token 的 JS 代码。
 // 它会优先使用缓存。
-func (p *Provider) getScripts(fresh bool) (string, error) {
+func (p *Provider) getScripts(ctx context.Context, fresh bool) (string, error) {
 	if p.jsCode.isValid() {
 		return p.jsCode.Value, nil
 	}
@@ -202,8 +206,8 @@ func (p *Provider) getScripts(fresh bool) (string, error) {
 		header.Set("x-vqd-accept", "1")
 	}
 
-	logger.Infof("Get scripts from /duckchat/v1/status")
-	response, err := p.client.Request(httpclient.GET, "https://duck.ai/duckchat/v1/status", header, nil, nil)
+	logger.Ctx(ctx).Infof("Get scripts from /duckchat/v1/status")
+	response, err := p.client.Request(ctx, httpclient.GET, "https://duck.ai/duckchat/v1/status", header, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -230,9 +234,10 @@ func (p *Provider) getScripts(fresh bool) (string, error) {
 }
 
 // PostConversation 发送聊天请求到 DuckAI API。
-func (p *Provider) PostConversation(request duckgotypes.ApiRequest) (*http.Response, error) {
+// ctx 通常是客户端请求的 context：客户端断开或超时后，token 刷新、重试和响应体的读取都会被中止。
+func (p *Provider) PostConversation(ctx context.Context, request duckgotypes.ApiRequest) (*http.Response, error) {
 	if os.Getenv("DUCKAI_BROWSER_CHAT") != "0" {
-		return p.PostConversationViaBrowser(request)
+		return p.PostConversationViaBrowser(ctx, request)
 	}
 
 	if p.proxyURL != "" {
@@ -246,24 +251,28 @@ func (p *Provider) PostConversation(request duckgotypes.ApiRequest) (*http.Respo
 
 	var lastErr error
 	for attempt := 0; attempt < 4; attempt++ {
-		token, err := p.GetToken()
+		token, err := p.GetToken(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get a valid token for chat: %w", err)
 		}
 
+		reportStatus(ctx, Status{Stage: "waiting_for_upstream"})
 		header := createHeader()
 		header.Set("accept", "text/event-stream")
 		header.Set("x-vqd-hash-1", token)
 		header.Set("x-fe-signals", makeFESignals())
 		header.Set("x-fe-version", p.feVersion)
 
-		response, err := p.client.Request(httpclient.POST, "https://duck.ai/duckchat/v1/chat", header, sessionCookies(), bytes.NewBuffer(bodyJSON))
+		response, err := p.client.Request(ctx, httpclient.POST, "https://duck.ai/duckchat/v1/chat", header, sessionCookies(), bytes.NewBuffer(bodyJSON))
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			lastErr = err
 			continue
 		}
 
-		p.updateScriptsFromHeader(response.Header)
+		p.updateScriptsFromHeader(ctx, response.Header)
 		if response.StatusCode != http.StatusTeapot {
 			return response, nil
 		}
@@ -271,14 +280,18 @@ func (p *Provider) PostConversation(request duckgotypes.ApiRequest) (*http.Respo
 		body, _ := io.ReadAll(response.Body)
 		response.Body.Close()
 		lastErr = fmt.Errorf("duck.ai challenge rejected request: %s", string(body))
-		p.InvalidateCache()
-		time.Sleep(time.Duration(300+attempt*400+rand.Intn(250)) * time.Millisecond)
+		p.InvalidateCache(ctx)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(300+attempt*400+rand.Intn(250)) * time.Millisecond):
+		}
 	}
 	return nil, lastErr
 }
 
 // updateScriptsFromHeader 从响应头中提取并更新缓存的 JS 代码。
-func (p *Provider) updateScriptsFromHeader(header http.Header) {
+func (p *Provider) updateScriptsFromHeader(ctx context.Context, header http.Header) {
 	base64EncodedJs := header.Get("x-vqd-hash-1")
 	if base64EncodedJs == "" {
 		return
@@ -286,21 +299,24 @@ func (p *Provider) updateScriptsFromHeader(header http.Header) {
 
 	decodedJsBytes, err := base64.StdEncoding.DecodeString(base64EncodedJs)
 	if err != nil {
-		logger.Errorf("Error decoding new script from header: %v", err)
+		logger.Ctx(ctx).Errorf("Error decoding new script from header: %v", err)
 		return
 	}
 
-	p.tokenMutex.Lock()
+	if err := p.tokenMutex.Lock(ctx); err != nil {
+		return
+	}
 	defer p.tokenMutex.Unlock()
 	p.jsCode = cachedItem[string]{
 		Value: string(decodedJsBytes),
 		// 使用环境变量配置的缓存时间
 		ExpireAt: time.Now().Add(p.scriptsCacheDuration),
 	}
-	logger.Debugf("Updated JS scripts from response header.")
+	logger.Ctx(ctx).Debugf("Updated JS scripts from response header.")
 }
 
 func (p *Provider) warmSession() {
+	ctx := context.Background()
 	header := createHeader()
 	header.Set("accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	header.Set("sec-fetch-dest", "document")
@@ -309,11 +325,11 @@ func (p *Provider) warmSession() {
 	header.Set("sec-fetch-user", "?1")
 	header.Set("upgrade-insecure-requests", "1")
 
-	if resp, err := p.client.Request(httpclient.GET, "https://duck.ai/", header, sessionCookies(), nil); err == nil && resp != nil {
+	if resp, err := p.client.Request(ctx, httpclient.GET, "https://duck.ai/", header, sessionCookies(), nil); err == nil && resp != nil {
 		io.Copy(io.Discard, resp.Body)
 		resp.Body.Close()
 	}
-	if resp, err := p.client.Request(httpclient.GET, "https://duckduckgo.com/?q=DuckDuckGo+AI+Chat&ia=chat&duckai=1", header, sessionCookies(), nil); err == nil && resp != nil {
+	if resp, err := p.client.Request(ctx, httpclient.GET, "https://duckduckgo.com/?q=DuckDuckGo+AI+Chat&ia=chat&duckai=1", header, sessionCookies(), nil); err == nil && resp != nil {
 		io.Copy(io.Discard, resp.Body)
 		resp.Body.Close()
 	}
@@ -0,0 +1,65 @@
+package duckgo
+
+import "strings"
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// thinkSplitter 把模型输出开头 <think>...</think> 中的内容分离为推理内容。
+// 标签可能被拆分到多个 chunk 中，无法确定时相关文本会被暂存。
+type thinkSplitter struct {
+	state   int // thinkUndecided / thinkInside / thinkDone
+	pending string
+}
+
+const (
+	thinkUndecided = iota
+	thinkInside
+	thinkDone
+)
+
+// push 追加一段上游输出，返回其中的推理内容和正文。
+func (s *thinkSplitter) push(text string) (reasoning, content string) {
+	switch s.state {
+	case thinkDone:
+		return "", text
+	case thinkUndecided:
+		buffer := s.pending + text
+		trimmed := strings.TrimLeft(buffer, " \t\r\n")
+		switch {
+		case strings.HasPrefix(trimmed, thinkOpenTag):
+			s.state = thinkInside
+			s.pending = ""
+			return s.push(trimmed[len(thinkOpenTag):])
+		case strings.HasPrefix(thinkOpenTag, trimmed):
+			s.pending = buffer
+			return "", ""
+		default:
+			s.state = thinkDone
+			s.pending = ""
+			return "", buffer
+		}
+	default:
+		buffer := s.pending + text
+		if idx := strings.Index(buffer, thinkCloseTag); idx >= 0 {
+			s.state = thinkDone
+			s.pending = ""
+			return buffer[:idx], strings.TrimLeft(buffer[idx+len(thinkCloseTag):], "\r\n")
+		}
+		hold := suffixPrefixOverlap(buffer, thinkCloseTag)
+		s.pending = buffer[len(buffer)-hold:]
+		return buffer[:len(buffer)-hold], ""
+	}
+}
+
+// flush 返回暂存的剩余文本，在上游结束时调用。
+func (s *thinkSplitter) flush() (reasoning, content string) {
+	rest := s.pending
+	s.pending = ""
+	if s.state == thinkInside {
+		return rest, ""
+	}
+	return "", rest
+}
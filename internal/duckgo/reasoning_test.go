@@ -0,0 +1,39 @@
+package duckgo
+
+import (
+	duckgotypes "aurora/typings/duckgo"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadStreamSeparatesThinkBlock(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"message":"\n<thi","model":"gpt-5-mini"}`,
+		`data: {"message":"nk>step one, ","model":"gpt-5-mini"}`,
+		`data: {"message":"step two</th","model":"gpt-5-mini"}`,
+		`data: {"message":"ink>\n\nAnswer","model":"gpt-5-mini"}`,
+		`data: {"action":"reasoning","message":"!","model":"gpt-5-mini"}`,
+		`data: [DONE]`,
+		"",
+	}, "\n")
+	var reasoning, content strings.Builder
+	result := ReadStream(context.Background(), strings.NewReader(body), duckgotypes.ApiRequest{Model: "gpt-5-mini"}, func(delta StreamDelta) error {
+		reasoning.WriteString(delta.Reasoning)
+		content.WriteString(delta.Content)
+		return nil
+	})
+	if result.Reasoning != "step one, step two!" || reasoning.String() != result.Reasoning {
+		t.Fatalf("unexpected reasoning %q / %q", result.Reasoning, reasoning.String())
+	}
+	if result.Text != "Answer" || content.String() != result.Text {
+		t.Fatalf("unexpected content %q / %q", result.Text, content.String())
+	}
+}
+
+func TestThinkSplitterPassesPlainText(t *testing.T) {
+	var s thinkSplitter
+	if reasoning, content := s.push("<b>bold</b>"); reasoning != "" || content != "<b>bold</b>" {
+		t.Fatalf("unexpected split %q / %q", reasoning, content)
+	}
+}
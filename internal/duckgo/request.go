@@ -2,13 +2,19 @@ package duckgo
 
 import (
 	"aurora/httpclient"
+	"aurora/internal/toolcall"
+	"aurora/logger"
 	duckgotypes "aurora/typings/duckgo"
 	officialtypes "aurora/typings/official"
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,58 +33,222 @@ func createHeader() httpclient.AuroraHeaders {
 	return header
 }
 
-func HandleRequestError(c *gin.Context, response *http.Response, provider *Provider) bool {
+// UpstreamError 描述上游返回的非 200 响应。
+type UpstreamError struct {
+	StatusCode int
+	Status     string
+	Detail     any    // 上游 JSON 中的 detail 字段（如有）
+	Body       string // 原始响应体
+	ReadFailed bool   // 读取响应体失败
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Detail != nil {
+		return fmt.Sprint(e.Detail)
+	}
+	if e.ReadFailed {
+		return "Failed to read error response body"
+	}
+	return "Unknown error from upstream API"
+}
+
+// CheckResponse 检查上游响应状态。非 200 时读取响应体并返回 *UpstreamError，
+// 遇到 418 会同时清空 Provider 的缓存。
+func CheckResponse(ctx context.Context, response *http.Response, provider *Provider) *UpstreamError {
 	if response.StatusCode == http.StatusOK {
-		return false
+		return nil
 	}
 
 	if response.StatusCode == http.StatusTeapot {
-		provider.InvalidateCache()
+		provider.InvalidateCache(ctx)
 	}
 
+	upstreamErr := &UpstreamError{StatusCode: response.StatusCode, Status: response.Status}
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
-		c.JSON(response.StatusCode, gin.H{"error": gin.H{
-			"message": "Failed to read error response body",
-			"type":    "internal_server_error",
-		}})
-		return true
+		upstreamErr.ReadFailed = true
+		return upstreamErr
 	}
+	upstreamErr.Body = string(body)
 
 	var errorResponse map[string]any
 	if json.Unmarshal(body, &errorResponse) == nil && errorResponse["detail"] != nil {
-		c.JSON(response.StatusCode, gin.H{"error": gin.H{
-			"message": errorResponse["detail"],
-			"type":    response.Status,
+		upstreamErr.Detail = errorResponse["detail"]
+	}
+	return upstreamErr
+}
+
+func HandleRequestError(c *gin.Context, response *http.Response, provider *Provider) bool {
+	upstreamErr := CheckResponse(c.Request.Context(), response, provider)
+	if upstreamErr == nil {
+		return false
+	}
+	WriteUpstreamError(c, upstreamErr)
+	return true
+}
+
+// WriteError 以 {"error": body} 的形式写出错误。
+// 心跳已经提前写出 SSE 响应头时状态码无法再修改，错误改为以 SSE 事件发送。
+func WriteError(c *gin.Context, status int, body any) {
+	if c.Writer.Written() {
+		event, _ := json.Marshal(gin.H{"error": body})
+		c.Writer.WriteString("data: " + string(event) + "\n\n")
+		c.Writer.Flush()
+		return
+	}
+	c.JSON(status, gin.H{"error": body})
+}
+
+// WriteUpstreamError 以 OpenAI 错误格式写出上游的错误响应。
+func WriteUpstreamError(c *gin.Context, upstreamErr *UpstreamError) {
+	switch {
+	case upstreamErr.ReadFailed:
+		WriteError(c, upstreamErr.StatusCode, gin.H{
+			"message": upstreamErr.Error(),
+			"type":    "internal_server_error",
+		})
+	case upstreamErr.Detail != nil:
+		WriteError(c, upstreamErr.StatusCode, gin.H{
+			"message": upstreamErr.Detail,
+			"type":    upstreamErr.Status,
 			"code":    "upstream_error",
-		}})
-	} else {
-		c.JSON(response.StatusCode, gin.H{"error": gin.H{
-			"message": "Unknown error from upstream API",
+		})
+	default:
+		WriteError(c, upstreamErr.StatusCode, gin.H{
+			"message": upstreamErr.Error(),
 			"type":    "internal_server_error",
-			"details": string(body),
-		}})
+			"details": upstreamErr.Body,
+		})
 	}
-	return true
 }
 
-func StreamHandler(c *gin.Context, response *http.Response, originalRequest duckgotypes.ApiRequest, stream bool) string {
-	contentType := "text/event-stream; charset=utf-8"
-	if !stream {
-		contentType = "application/json; charset=utf-8"
+// StreamError 表示上游在输出过程中报告的错误（error / abort 事件），
+// 或者响应在 [DONE] 之前中断。此时已经读取到的内容只是部分输出。
+type StreamError struct {
+	Type    string // 上游的错误类型，例如 ERR_CONVERSATION_LIMIT；连接中断时为 stream_aborted
+	Status  int    // 上游给出的状态码，没有时为 0
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	if e.Message != "" {
+		return "Upstream stream failed (" + e.Type + "): " + e.Message
 	}
-	c.Header("Content-Type", contentType)
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
+	return "Upstream stream failed (" + e.Type + ")"
+}
 
-	reader := bufio.NewReader(response.Body)
-	var fullMessageBuilder strings.Builder
+// StatusCode 返回非流式请求应使用的状态码。上游给出 429 或 5xx 时原样使用，其余情况为 502。
+func (e *StreamError) StatusCode() int {
+	if e.Status == http.StatusTooManyRequests || e.Status >= 500 {
+		return e.Status
+	}
+	return http.StatusBadGateway
+}
+
+func (e *StreamError) openAIError() gin.H {
+	return gin.H{
+		"message": e.Error(),
+		"type":    "upstream_error",
+		"code":    e.Type,
+	}
+}
+
+// WriteStreamError 以 OpenAI 错误格式写出非流式请求的上游中途错误。
+func WriteStreamError(c *gin.Context, streamErr *StreamError) {
+	WriteError(c, streamErr.StatusCode(), streamErr.openAIError())
+}
+
+// StreamErrorEvent 返回流式响应中途出错时发送的 OpenAI 风格 SSE 事件。
+func StreamErrorEvent(streamErr *StreamError) string {
+	event, _ := json.Marshal(gin.H{"error": streamErr.openAIError()})
+	return "data: " + string(event) + "\n\n"
+}
+
+// streamErrorFrom 识别上游的 error / abort 事件，例如
+// {"action":"error","status":429,"type":"ERR_CONVERSATION_LIMIT"}。
+func streamErrorFrom(response duckgotypes.ApiResponse) *StreamError {
+	if response.Action != "error" && response.Action != "abort" {
+		return nil
+	}
+	streamErr := &StreamError{Type: response.Type, Status: response.Status, Message: response.Message}
+	if streamErr.Type == "" {
+		streamErr.Type = "upstream_" + response.Action
+	}
+	return streamErr
+}
+
+// StreamDelta 是从上游读取到的一段可输出内容。Content 与 Reasoning 可能只有一个非空。
+type StreamDelta struct {
+	Content   string
+	Reasoning string // 推理模型的思考内容
+	Model     string
+}
 
+// StreamResult 汇总一次上游会话的读取结果。
+type StreamResult struct {
+	Text         string          // 聚合后的完整回复
+	Reasoning    string          // 聚合后的推理内容
+	Model        string          // 上游实际返回的模型
+	FinishReason string          // stop / length / tool_calls
+	StopSequence string          // 命中的停止序列，未命中时为空
+	ToolCalls    []toolcall.Call // 解析出的工具调用
+	Citations    []Citation      // 上游搜索工具返回的来源
+	Err          *StreamError    // 上游中途报错或中断时非 nil
+}
+
+// CompletionText 返回用于统计输出 token 的文本，包括推理内容以及工具调用的函数名和参数。
+func (r StreamResult) CompletionText() string {
+	text := r.Reasoning + r.Text
+	for _, call := range r.ToolCalls {
+		text += call.Name + call.Arguments
+	}
+	return text
+}
+
+// ReadStream 逐行读取 duck.ai 的 SSE 响应，每得到一段可输出的文本就回调 onDelta。
+// 请求中的 StopSequences 会跨 chunk 匹配，命中后立即停止读取；
+// 输出达到 MaxTokens 时截断到上限处并以 length 结束。
+// 推理内容（单独的 reasoning 字段或输出开头的 <think> 块）不参与停止序列匹配，
+// 以 Reasoning 回调并汇总到 result.Reasoning。
+// onDelta 返回 error（例如客户端已断开）时同样停止读取。
+// 上游发送 error / abort 事件、读取失败或在 [DONE] 之前结束时，result.Err 非 nil。
+// ctx 仅用于给日志附加请求 ID。
+func ReadStream(ctx context.Context, body io.Reader, request duckgotypes.ApiRequest, onDelta func(StreamDelta) error) StreamResult {
+	reader := bufio.NewReader(body)
+	matcher := newStopMatcher(request.StopSequences)
+	limiter := newTokenLimiter(request.MaxTokens)
+	var splitter thinkSplitter
+	var citations citationCollector
+	result := StreamResult{Model: request.Model, FinishReason: "stop"}
+	var fullMessageBuilder, reasoningBuilder strings.Builder
+
+	emit := func(text string) error {
+		if text == "" {
+			return nil
+		}
+		fullMessageBuilder.WriteString(text)
+		return onDelta(StreamDelta{Content: text, Model: result.Model})
+	}
+	emitReasoning := func(text string) error {
+		if text == "" {
+			return nil
+		}
+		reasoningBuilder.WriteString(text)
+		return onDelta(StreamDelta{Reasoning: text, Model: result.Model})
+	}
+	finish := func() StreamResult {
+		result.Text = fullMessageBuilder.String()
+		result.Reasoning = reasoningBuilder.String()
+		result.Citations = citations.citations
+		return result
+	}
+
+	done := false
 	for {
 		line, err := reader.ReadString('\n')
 		if err != nil {
-			if err == io.EOF {
-				break
+			if err != io.EOF {
+				result.Err = &StreamError{Type: "stream_aborted", Message: err.Error()}
 			}
 			break
 		}
@@ -88,33 +258,255 @@ func StreamHandler(c *gin.Context, response *http.Response, originalRequest duck
 		}
 		data := strings.TrimPrefix(line, "data: ")
 
-		if stream && strings.HasPrefix(data, "[DONE]") {
-			finalChunk := officialtypes.StopChunkWithModel("stop", originalRequest.Model)
-			c.Writer.WriteString("data: " + finalChunk.String() + "\n\n")
-			c.Writer.Flush()
+		if strings.HasPrefix(data, "[DONE]") {
+			done = true
 			break
 		}
 
 		var apiResponse duckgotypes.ApiResponse
 		if err := json.Unmarshal([]byte(data), &apiResponse); err != nil {
+			logger.Ctx(ctx).Warnf("Skipping unparseable upstream event: %s", strings.TrimSpace(data))
 			continue
 		}
+		if result.Err = streamErrorFrom(apiResponse); result.Err != nil {
+			break
+		}
+		citations.add(data)
+		if apiResponse.Model != "" {
+			result.Model = apiResponse.Model
+		}
 
-		if apiResponse.Message == "" {
+		reasoning, message := apiResponse.Reasoning, apiResponse.Message
+		if apiResponse.Action == "reasoning" {
+			reasoning, message = reasoning+message, ""
+		}
+		thought, message := splitter.push(message)
+		if err := emitReasoning(reasoning + thought); err != nil {
+			return finish()
+		}
+		if message == "" {
 			continue
 		}
 
-		fullMessageBuilder.WriteString(apiResponse.Message)
+		text, stopSequence, matched := matcher.push(message)
+		text, exceeded := limiter.push(text)
+		if err := emit(text); err != nil {
+			return finish()
+		}
+		if exceeded {
+			result.FinishReason = "length"
+			return finish()
+		}
+		if matched {
+			result.StopSequence = stopSequence
+			return finish()
+		}
+	}
+
+	if result.Err == nil && !done {
+		result.Err = &StreamError{Type: "stream_aborted", Message: "upstream closed the stream before [DONE]"}
+	}
+
+	thought, message := splitter.flush()
+	_ = emitReasoning(thought)
+	text, stopSequence, matched := matcher.push(message)
+	if matched {
+		result.StopSequence = stopSequence
+	} else {
+		text += matcher.flush()
+	}
+	text, exceeded := limiter.push(text)
+	if exceeded {
+		result.FinishReason = "length"
+	}
+	_ = emit(text)
+	return finish()
+}
+
+// StreamOptions 控制 StreamHandler 的输出方式。
+type StreamOptions struct {
+	Stream       bool                       // 是否以 SSE 流式输出
+	IncludeUsage bool                       // 结束后额外发送带用量的 chunk
+	Meta         officialtypes.ResponseMeta // 本次响应所有 chunk 共用的 ID、时间和模型
+	Tools        *ToolOptions               // 非 nil 时从模型输出中解析工具调用
+	JSON         *JSONOptions               // 非 nil 时校验 JSON 输出，输出在校验通过后才发送
+	Index        int                        // chunk 中的 choices[].index，n > 1 时区分各个 choice
+	Lock         sync.Locker                // 非 nil 时与其他 StreamHandler 共用同一个连接，写入前加锁
+}
+
+// ToolOptions 启用对模型输出中工具调用的解析。
+type ToolOptions struct {
+	Tools []officialtypes.Tool
+	// Retry 在工具调用无法解析时调用一次，返回上游重新生成的完整输出。
+	Retry func(previous string, parseErr error) (string, error)
+}
+
+// StreamHandler 读取上游响应，流式请求时以 OpenAI chat.completion.chunk 格式输出。
+// 启用工具调用时，返回结果中的 Text 只包含工具调用之前的文本，调用本身放在 ToolCalls 中。
+// 上游中途出错时，流式请求以 error 事件结束，非流式请求由调用方根据 result.Err 写出错误。
+func StreamHandler(c *gin.Context, response *http.Response, originalRequest duckgotypes.ApiRequest, options StreamOptions) StreamResult {
+	lock := options.Lock
+	if lock == nil {
+		lock = noopLocker{}
+	}
+	contentType := "text/event-stream; charset=utf-8"
+	if !options.Stream {
+		contentType = "application/json; charset=utf-8"
+	}
+	lock.Lock()
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	lock.Unlock()
+
+	var writeErr error
+	writeChunk := func(chunk officialtypes.ChatCompletionChunk) error {
+		if writeErr != nil {
+			return writeErr
+		}
+		for i := range chunk.Choices {
+			chunk.Choices[i].Index = options.Index
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		if _, writeErr = c.Writer.WriteString("data: " + chunk.String() + "\n\n"); writeErr != nil {
+			return writeErr
+		}
+		c.Writer.Flush()
+		return nil
+	}
+	writeContent := func(text string) error {
+		if !options.Stream || text == "" {
+			return nil
+		}
+		return writeChunk(officialtypes.NewChatCompletionChunk(options.Meta, text))
+	}
 
-		if stream {
-			chunk := officialtypes.NewChatCompletionChunkWithModel(apiResponse.Message, apiResponse.Model)
-			responseString := "data: " + chunk.String() + "\n\n"
-			if _, err := c.Writer.WriteString(responseString); err != nil {
-				break
+	var detector *toolcall.Detector
+	if options.Tools != nil {
+		detector = toolcall.NewDetector()
+	}
+	result := ReadStream(c.Request.Context(), response.Body, originalRequest, func(delta StreamDelta) error {
+		if delta.Reasoning != "" {
+			if !options.Stream {
+				return nil
 			}
+			return writeChunk(officialtypes.ReasoningChunk(options.Meta, delta.Reasoning))
+		}
+		text := delta.Content
+		if detector != nil {
+			text = detector.Push(text)
+		}
+		if options.JSON != nil {
+			// JSON 需要在完整输出校验通过后才能发送
+			return nil
+		}
+		return writeContent(text)
+	})
+	if writeErr != nil {
+		return result
+	}
+	if result.Err != nil {
+		log := logger.Ctx(c.Request.Context())
+		if errors.Is(c.Request.Context().Err(), context.Canceled) {
+			// 客户端已断开，上游读取是随请求一起被取消的
+			log.Infof("Client disconnected, upstream stream aborted")
+			return result
+		}
+		log.Warnf("%v", result.Err)
+		if options.Stream {
+			lock.Lock()
+			defer lock.Unlock()
+			c.Writer.WriteString(StreamErrorEvent(result.Err))
 			c.Writer.Flush()
 		}
+		return result
+	}
+
+	if detector != nil && detector.Found() {
+		resolveToolCalls(c.Request.Context(), &result, options.Tools)
+	}
+	switch {
+	case len(result.ToolCalls) > 0:
+		if options.Stream {
+			writeToolCallChunks(writeChunk, options.Meta, result.ToolCalls)
+		}
+	case options.JSON != nil:
+		enforceJSON(c.Request.Context(), &result, options.JSON)
+		writeContent(result.Text)
+	case detector != nil:
+		if detector.Found() {
+			writeContent(detector.Captured())
+		} else {
+			writeContent(detector.Flush())
+		}
+	}
+
+	if options.Stream {
+		if annotations := result.Annotations(); len(annotations) > 0 {
+			writeChunk(officialtypes.AnnotationsChunk(options.Meta, annotations))
+		}
+		writeChunk(officialtypes.StopChunk(options.Meta, result.FinishReason))
+		if options.IncludeUsage {
+			writeChunk(officialtypes.UsageChunk(options.Meta, NewUsage(originalRequest, result.CompletionText())))
+		}
 	}
 
-	return fullMessageBuilder.String()
+	return result
+}
+
+// noopLocker 用于只有一个 StreamHandler 写入连接的情况。
+type noopLocker struct{}
+
+func (noopLocker) Lock()   {}
+func (noopLocker) Unlock() {}
+
+// resolveToolCalls 解析捕获到的工具调用，解析失败时通过 Retry 让上游重新输出一次。
+// 仍然失败时放弃解析，原始输出作为普通文本保留在 result.Text 中。
+func resolveToolCalls(ctx context.Context, result *StreamResult, options *ToolOptions) {
+	content, calls, err := toolcall.Parse(result.Text, options.Tools)
+	if err != nil && options.Retry != nil {
+		logger.Ctx(ctx).Warnf("Retrying malformed tool call: %v", err)
+		if raw, retryErr := options.Retry(result.Text, err); retryErr == nil {
+			content, calls, err = toolcall.Parse(raw, options.Tools)
+		} else {
+			logger.Ctx(ctx).Warnf("Tool call retry failed: %v", retryErr)
+		}
+	}
+	if err != nil || len(calls) == 0 {
+		logger.Ctx(ctx).Warnf("Giving up on malformed tool call, returning raw output: %v", err)
+		return
+	}
+	result.Text = content
+	result.ToolCalls = calls
+	result.FinishReason = "tool_calls"
+}
+
+// writeToolCallChunks 按 OpenAI 的流式格式输出工具调用：
+// 每个调用先发送带 id 和函数名的片段，再把 arguments 拆分为多个片段发送。
+func writeToolCallChunks(writeChunk func(officialtypes.ChatCompletionChunk) error, meta officialtypes.ResponseMeta, calls []toolcall.Call) {
+	const fragmentSize = 32
+	for i, call := range calls {
+		index := i
+		header := officialtypes.ToolCall{
+			Index:    &index,
+			ID:       call.ID,
+			Type:     "function",
+			Function: officialtypes.FunctionCall{Name: call.Name},
+		}
+		if writeChunk(officialtypes.ToolCallChunk(meta, []officialtypes.ToolCall{header})) != nil {
+			return
+		}
+		arguments := []rune(call.Arguments)
+		for start := 0; start < len(arguments); start += fragmentSize {
+			end := min(start+fragmentSize, len(arguments))
+			fragment := officialtypes.ToolCall{
+				Index:    &index,
+				Function: officialtypes.FunctionCall{Arguments: string(arguments[start:end])},
+			}
+			if writeChunk(officialtypes.ToolCallChunk(meta, []officialtypes.ToolCall{fragment})) != nil {
+				return
+			}
+		}
+	}
 }
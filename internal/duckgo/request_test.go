@@ -0,0 +1,42 @@
+package duckgo
+
+import (
+	duckgotypes "aurora/typings/duckgo"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func readTestStream(lines ...string) StreamResult {
+	body := strings.Join(append(lines, ""), "\n")
+	return ReadStream(context.Background(), strings.NewReader(body), duckgotypes.ApiRequest{Model: "gpt-4o-mini"}, func(StreamDelta) error { return nil })
+}
+
+func TestReadStreamErrorAction(t *testing.T) {
+	result := readTestStream(
+		`data: {"message":"Hello","model":"gpt-4o-mini"}`,
+		`data: not json`,
+		`data: {"action":"error","status":429,"type":"ERR_CONVERSATION_LIMIT"}`,
+		`data: {"message":" ignored","model":"gpt-4o-mini"}`,
+		`data: [DONE]`,
+	)
+	if result.Err == nil || result.Err.Type != "ERR_CONVERSATION_LIMIT" || result.Err.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("unexpected error %+v", result.Err)
+	}
+	if result.Text != "Hello" {
+		t.Fatalf("unexpected partial text %q", result.Text)
+	}
+}
+
+func TestReadStreamTruncated(t *testing.T) {
+	result := readTestStream(`data: {"message":"Hel","model":"gpt-4o-mini"}`)
+	if result.Err == nil || result.Err.Type != "stream_aborted" || result.Err.StatusCode() != http.StatusBadGateway {
+		t.Fatalf("unexpected error %+v", result.Err)
+	}
+
+	result = readTestStream(`data: {"message":"Hello","model":"gpt-4o-mini"}`, `data: [DONE]`)
+	if result.Err != nil {
+		t.Fatalf("unexpected error %v", result.Err)
+	}
+}
@@ -0,0 +1,72 @@
+package duckgo
+
+import "strings"
+
+// stopMatcher 在流式输出中匹配停止序列。
+// 由于停止序列可能被拆分到多个 chunk 中，可能构成停止序列前缀的尾部文本会被暂存，
+// 直到能确定它不属于任何停止序列时才输出。
+type stopMatcher struct {
+	sequences []string
+	pending   string
+}
+
+func newStopMatcher(sequences []string) *stopMatcher {
+	m := &stopMatcher{}
+	for _, seq := range sequences {
+		if seq != "" {
+			m.sequences = append(m.sequences, seq)
+		}
+	}
+	return m
+}
+
+// push 追加一段文本，返回可以安全输出的部分。
+// 命中停止序列时 matched 为 true，返回的文本截止到停止序列之前。
+func (m *stopMatcher) push(text string) (output string, sequence string, matched bool) {
+	if len(m.sequences) == 0 {
+		return text, "", false
+	}
+
+	buffer := m.pending + text
+	cut := -1
+	for _, seq := range m.sequences {
+		if idx := strings.Index(buffer, seq); idx >= 0 && (cut < 0 || idx < cut) {
+			cut = idx
+			sequence = seq
+		}
+	}
+	if cut >= 0 {
+		m.pending = ""
+		return buffer[:cut], sequence, true
+	}
+
+	hold := 0
+	for _, seq := range m.sequences {
+		if n := suffixPrefixOverlap(buffer, seq); n > hold {
+			hold = n
+		}
+	}
+	m.pending = buffer[len(buffer)-hold:]
+	return buffer[:len(buffer)-hold], "", false
+}
+
+// flush 返回暂存的剩余文本，在上游正常结束时调用。
+func (m *stopMatcher) flush() string {
+	rest := m.pending
+	m.pending = ""
+	return rest
+}
+
+// suffixPrefixOverlap 返回 text 的后缀与 seq 的前缀重叠的最大长度。
+func suffixPrefixOverlap(text, seq string) int {
+	maxLen := len(seq) - 1
+	if maxLen > len(text) {
+		maxLen = len(text)
+	}
+	for n := maxLen; n > 0; n-- {
+		if strings.HasSuffix(text, seq[:n]) {
+			return n
+		}
+	}
+	return 0
+}
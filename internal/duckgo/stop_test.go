@@ -0,0 +1,78 @@
+package duckgo
+
+import (
+	duckgotypes "aurora/typings/duckgo"
+	"aurora/util"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStopMatcherAcrossChunks(t *testing.T) {
+	matcher := newStopMatcher([]string{"\n\nHuman:"})
+	var out strings.Builder
+	for _, chunk := range []string{"Hello", " world\n", "\nHum", "an: ignored"} {
+		text, seq, matched := matcher.push(chunk)
+		out.WriteString(text)
+		if matched {
+			if seq != "\n\nHuman:" {
+				t.Fatalf("unexpected sequence %q", seq)
+			}
+			if out.String() != "Hello world" {
+				t.Fatalf("unexpected output %q", out.String())
+			}
+			return
+		}
+	}
+	t.Fatal("stop sequence was not matched")
+}
+
+func TestStopMatcherFlushesPartialPrefix(t *testing.T) {
+	matcher := newStopMatcher([]string{"END"})
+	text, _, matched := matcher.push("the EN")
+	if matched || text != "the " {
+		t.Fatalf("unexpected push result %q matched=%v", text, matched)
+	}
+	if rest := matcher.flush(); rest != "EN" {
+		t.Fatalf("unexpected flush %q", rest)
+	}
+}
+
+func TestReadStreamStopSequence(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"message":"one, ","model":"gpt-4o-mini"}`,
+		`data: {"message":"two, thr","model":"gpt-4o-mini"}`,
+		`data: {"message":"ee","model":"gpt-4o-mini"}`,
+		`data: [DONE]`,
+		"",
+	}, "\n")
+	request := duckgotypes.ApiRequest{Model: "gpt-4o-mini", StopSequences: []string{"three"}}
+	result := ReadStream(context.Background(), strings.NewReader(body), request, func(StreamDelta) error { return nil })
+	if result.Text != "one, two, " || result.StopSequence != "three" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestReadStreamMaxTokens(t *testing.T) {
+	full := "The quick brown fox jumps over the lazy dog. "
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, `data: {"message":"`+full+`","model":"gpt-4o-mini"}`)
+	}
+	body := strings.Join(append(lines, "data: [DONE]", ""), "\n")
+	request := duckgotypes.ApiRequest{Model: "gpt-4o-mini", MaxTokens: 25}
+	var streamed strings.Builder
+	result := ReadStream(context.Background(), strings.NewReader(body), request, func(delta StreamDelta) error {
+		streamed.WriteString(delta.Content)
+		return nil
+	})
+	if result.FinishReason != "length" {
+		t.Fatalf("unexpected finish reason %q", result.FinishReason)
+	}
+	if streamed.String() != result.Text || !strings.HasPrefix(strings.Repeat(full, 10), result.Text) {
+		t.Fatalf("unexpected text %q", result.Text)
+	}
+	if tokens := util.CountToken(result.Text); tokens > 25 || tokens < 20 {
+		t.Fatalf("unexpected token count %d", tokens)
+	}
+}
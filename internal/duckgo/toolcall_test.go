@@ -0,0 +1,24 @@
+package duckgo
+
+import (
+	officialtypes "aurora/typings/official"
+	"context"
+	"testing"
+)
+
+func TestResolveToolCallsUsesRetriedContent(t *testing.T) {
+	tools := []officialtypes.Tool{{
+		Type:     "function",
+		Function: officialtypes.FunctionDefinition{Name: "get_weather", Parameters: map[string]any{"type": "object"}},
+	}}
+	result := StreamResult{Text: "First try.\n<tool_call>not json</tool_call>"}
+	resolveToolCalls(context.Background(), &result, &ToolOptions{
+		Tools: tools,
+		Retry: func(string, error) (string, error) {
+			return "Second try.\n<tool_call>{\"name\": \"get_weather\", \"arguments\": {}}</tool_call>", nil
+		},
+	})
+	if result.Text != "Second try." || len(result.ToolCalls) != 1 || result.FinishReason != "tool_calls" {
+		t.Fatalf("unexpected result %q %+v %q", result.Text, result.ToolCalls, result.FinishReason)
+	}
+}
@@ -0,0 +1,41 @@
+package duckgo
+
+import (
+	duckgotypes "aurora/typings/duckgo"
+	officialtypes "aurora/typings/official"
+	"aurora/util"
+	"strings"
+)
+
+// CountPromptTokens 统计转换后请求中所有消息文本的 token 数，图片等非文本内容不计入。
+func CountPromptTokens(request duckgotypes.ApiRequest) int {
+	var b strings.Builder
+	for _, message := range request.Messages {
+		writeMessageText(&b, message)
+		b.WriteString("\n")
+	}
+	return util.CountToken(b.String())
+}
+
+// NewUsage 根据转换后的请求和聚合后的回复计算 OpenAI 格式的用量。
+func NewUsage(request duckgotypes.ApiRequest, completion string) officialtypes.Usage {
+	return officialtypes.NewUsage(CountPromptTokens(request), util.CountToken(completion))
+}
+
+func writeContentText(b *strings.Builder, content any) {
+	switch v := content.(type) {
+	case string:
+		b.WriteString(v)
+	case []any:
+		for _, part := range v {
+			switch p := part.(type) {
+			case duckgotypes.PartText:
+				b.WriteString(p.Text)
+			case map[string]any:
+				if text, ok := p["text"].(string); ok {
+					b.WriteString(text)
+				}
+			}
+		}
+	}
+}
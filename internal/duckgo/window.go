@@ -0,0 +1,174 @@
+package duckgo
+
+import (
+	"aurora/internal/models"
+	duckgotypes "aurora/typings/duckgo"
+	"aurora/util"
+	"fmt"
+	"strings"
+)
+
+// defaultKeepLastTurns 是 keep_last 策略在模型未配置 keep_last_turns 时保留的轮数。
+const defaultKeepLastTurns = 8
+
+// maxSummaryTokens 限制 summarize 策略生成的总结长度。
+const maxSummaryTokens = 1024
+
+// ContextOptions 描述请求的上下文预算以及超出预算时的裁剪方式。
+type ContextOptions struct {
+	Limit     int    // 模型的上下文长度，0 表示不限制
+	Strategy  string // models.ContextDropOldest / ContextKeepLast / ContextSummarize
+	KeepTurns int    // keep_last 保留的轮数
+	// Summarize 让上游总结一段对话记录，summarize 策略使用；失败时退化为直接丢弃
+	Summarize func(transcript string) (string, error)
+}
+
+// ContextLengthError 表示即使只保留固定消息和最后一轮，请求仍然超出模型的上下文长度。
+type ContextLengthError struct {
+	Limit            int
+	PromptTokens     int
+	CompletionTokens int // 请求预留的输出 token（max_tokens）
+}
+
+func (e *ContextLengthError) Error() string {
+	return fmt.Sprintf("This model's maximum context length is %d tokens. However, you requested %d tokens (%d in the messages, %d in the completion). Please reduce the length of the messages or completion.",
+		e.Limit, e.PromptTokens+e.CompletionTokens, e.PromptTokens, e.CompletionTokens)
+}
+
+// ContextResult 记录裁剪的结果，用于日志。
+type ContextResult struct {
+	Dropped    int   // 被丢弃（或被总结）的消息数
+	Summarized bool  // 是否用总结代替了被丢弃的消息
+	SummaryErr error // 总结失败的原因，此时被总结的消息直接丢弃
+}
+
+// FitContext 在发送到上游之前把请求裁剪到模型的上下文预算之内，预算为上下文长度减去 max_tokens。
+// 开头的固定消息（request.PinnedMessages）和最后一轮对话始终保留，
+// 仍然超出预算时返回 *ContextLengthError。
+// 一轮从一条用户消息开始，包括其后的助手消息。
+func FitContext(request *duckgotypes.ApiRequest, options ContextOptions) (ContextResult, error) {
+	var result ContextResult
+	if options.Limit <= 0 {
+		return result, nil
+	}
+	budget := options.Limit - request.MaxTokens
+	messages := request.Messages
+	counts := make([]int, len(messages))
+	total := 0
+	for i, message := range messages {
+		counts[i] = messageTokens(message)
+		total += counts[i]
+	}
+	if total <= budget {
+		return result, nil
+	}
+
+	pinned := min(request.PinnedMessages, len(messages))
+	turns := turnStarts(messages, pinned)
+	tokensFrom := func(start int) int {
+		sum := 0
+		for _, count := range counts[start:] {
+			sum += count
+		}
+		return sum
+	}
+	pinnedTokens := total - tokensFrom(pinned)
+	last := len(turns) - 1
+	if last < 0 || pinnedTokens+tokensFrom(turns[last]) > budget {
+		minimal := total
+		if last >= 0 {
+			minimal = pinnedTokens + tokensFrom(turns[last])
+		}
+		return result, &ContextLengthError{Limit: options.Limit, PromptTokens: minimal, CompletionTokens: request.MaxTokens}
+	}
+
+	// keep 是保留下来的第一轮在 turns 中的下标
+	keep := 0
+	reserve := 0
+	switch options.Strategy {
+	case models.ContextKeepLast:
+		keepTurns := options.KeepTurns
+		if keepTurns <= 0 {
+			keepTurns = defaultKeepLastTurns
+		}
+		keep = max(0, len(turns)-keepTurns)
+	case models.ContextSummarize:
+		if options.Summarize != nil {
+			reserve = min(maxSummaryTokens, budget/4)
+		}
+	}
+	for keep < last && pinnedTokens+reserve+tokensFrom(turns[keep]) > budget {
+		keep++
+	}
+	if reserve > 0 && pinnedTokens+reserve+tokensFrom(turns[keep]) > budget {
+		// 只剩最后一轮也放不下总结，直接丢弃
+		reserve = 0
+	}
+
+	dropped := messages[pinned:turns[keep]]
+	trimmed := append([]any{}, messages[:pinned]...)
+	if reserve > 0 && len(dropped) > 0 {
+		transcript := util.TruncateToTokens(transcript(dropped), max(budget-maxSummaryTokens, maxSummaryTokens))
+		summary, err := options.Summarize(transcript)
+		if summary = strings.TrimSpace(summary); err == nil && summary != "" {
+			room := budget - pinnedTokens - tokensFrom(turns[keep]) - 16
+			trimmed = append(trimmed, duckgotypes.MessageUser{
+				Role:    "user",
+				Content: "[Summary of the earlier conversation]\n" + util.TruncateToTokens(summary, min(room, maxSummaryTokens)),
+			})
+			result.Summarized = true
+		} else {
+			result.SummaryErr = err
+		}
+	}
+	request.Messages = append(trimmed, messages[turns[keep]:]...)
+	request.PinnedMessages = pinned
+	result.Dropped = len(dropped)
+	return result, nil
+}
+
+// turnStarts 返回 messages[pinned:] 中每一轮的起始下标。固定消息之后如果先出现助手消息，它们单独算作一轮。
+func turnStarts(messages []any, pinned int) []int {
+	var starts []int
+	for i := pinned; i < len(messages); i++ {
+		if _, isUser := messages[i].(duckgotypes.MessageUser); isUser || i == pinned {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// messageTokens 估算一条消息的 token 数，图片等非文本内容不计入。
+func messageTokens(message any) int {
+	var b strings.Builder
+	writeMessageText(&b, message)
+	return util.CountToken(b.String()) + 4 // 角色和分隔符的开销
+}
+
+func writeMessageText(b *strings.Builder, message any) {
+	switch msg := message.(type) {
+	case duckgotypes.MessageUser:
+		writeContentText(b, msg.Content)
+	case duckgotypes.MessageAssistant:
+		b.WriteString(msg.Content)
+		writeContentText(b, msg.Parts)
+	}
+}
+
+// transcript 把消息渲染为 "User: ... / Assistant: ..." 形式的纯文本对话记录。
+func transcript(messages []any) string {
+	var b strings.Builder
+	for _, message := range messages {
+		switch message.(type) {
+		case duckgotypes.MessageUser:
+			b.WriteString("User: ")
+		case duckgotypes.MessageAssistant:
+			b.WriteString("Assistant: ")
+		default:
+			continue
+		}
+		writeMessageText(&b, message)
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
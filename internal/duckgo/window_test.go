@@ -0,0 +1,96 @@
+package duckgo
+
+import (
+	"aurora/internal/models"
+	duckgotypes "aurora/typings/duckgo"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// longRequest 返回一条固定的系统提示加上 turns 轮对话，每条消息约 100 个单词。
+func longRequest(turns int) duckgotypes.ApiRequest {
+	request := duckgotypes.ApiRequest{PinnedMessages: 1}
+	request.AddMessageUser("You are a helpful assistant.")
+	filler := strings.Repeat("word ", 100)
+	for i := 0; i < turns; i++ {
+		request.AddMessageUser("question " + filler)
+		request.AddMessageAssistant([]any{duckgotypes.PartText{Type: "text", Text: "answer " + filler}})
+	}
+	return request
+}
+
+func TestFitContextDropOldest(t *testing.T) {
+	request := longRequest(10)
+	full := CountPromptTokens(request)
+	result, err := FitContext(&request, ContextOptions{Limit: full / 2, Strategy: models.ContextDropOldest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Dropped == 0 || result.Dropped%2 != 0 {
+		t.Fatalf("expected whole turns to be dropped, got %d", result.Dropped)
+	}
+	if request.Messages[0].(duckgotypes.MessageUser).Content != "You are a helpful assistant." {
+		t.Fatalf("system prompt was dropped: %+v", request.Messages[0])
+	}
+	if _, ok := request.Messages[1].(duckgotypes.MessageUser); !ok {
+		t.Fatalf("kept history does not start with a user turn: %+v", request.Messages[1])
+	}
+	if CountPromptTokens(request) > full/2 {
+		t.Fatalf("request still exceeds the budget")
+	}
+}
+
+func TestFitContextKeepLast(t *testing.T) {
+	request := longRequest(10)
+	_, err := FitContext(&request, ContextOptions{Limit: CountPromptTokens(request) - 1, Strategy: models.ContextKeepLast, KeepTurns: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(request.Messages) != 1+3*2 {
+		t.Fatalf("expected system prompt plus 3 turns, got %d messages", len(request.Messages))
+	}
+}
+
+func TestFitContextSummarize(t *testing.T) {
+	request := longRequest(10)
+	var transcript string
+	result, err := FitContext(&request, ContextOptions{
+		Limit:    CountPromptTokens(request) / 2,
+		Strategy: models.ContextSummarize,
+		Summarize: func(text string) (string, error) {
+			transcript = text
+			return "the user asked ten questions", nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Summarized || !strings.HasPrefix(transcript, "User: question") {
+		t.Fatalf("unexpected result %+v, transcript %.40q", result, transcript)
+	}
+	summary := request.Messages[1].(duckgotypes.MessageUser).Content.(string)
+	if !strings.Contains(summary, "the user asked ten questions") {
+		t.Fatalf("summary not injected: %q", summary)
+	}
+
+	request = longRequest(10)
+	result, err = FitContext(&request, ContextOptions{
+		Limit:     CountPromptTokens(request) / 2,
+		Strategy:  models.ContextSummarize,
+		Summarize: func(string) (string, error) { return "", errors.New("upstream down") },
+	})
+	if err != nil || result.Summarized || result.SummaryErr == nil || result.Dropped == 0 {
+		t.Fatalf("expected fallback to dropping, got %+v, %v", result, err)
+	}
+}
+
+func TestFitContextTooLong(t *testing.T) {
+	request := longRequest(1)
+	request.MaxTokens = 50
+	_, err := FitContext(&request, ContextOptions{Limit: 100})
+	var lengthErr *ContextLengthError
+	if !errors.As(err, &lengthErr) || lengthErr.CompletionTokens != 50 {
+		t.Fatalf("expected ContextLengthError, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package files
+
+import (
+	"aurora/util"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Error 表示请求中的文件无法使用，应以 400 返回给客户端。
+type Error struct {
+	Filename string
+	Reason   string
+}
+
+func (e *Error) Error() string {
+	if e.Filename == "" {
+		return "Invalid file: " + e.Reason
+	}
+	return fmt.Sprintf("Invalid file %q: %s", e.Filename, e.Reason)
+}
+
+// Options 限制文件的大小和注入的文本长度。
+type Options struct {
+	MaxBytes  int // 解码后文件的最大字节数，0 表示不限制
+	MaxTokens int // 每个文件注入的最大 token 数，超出部分被截断，0 表示不限制
+}
+
+// Extract 解码文件（data URL 或纯 base64），提取其中的文本，
+// 并渲染为带文件名和类型标记的一段文本，超出 MaxTokens 时截断并附上说明。
+func Extract(filename, fileData string, options Options) (string, error) {
+	declared, data, err := decode(fileData)
+	if err != nil {
+		return "", &Error{Filename: filename, Reason: err.Error()}
+	}
+	if options.MaxBytes > 0 && len(data) > options.MaxBytes {
+		return "", &Error{Filename: filename, Reason: fmt.Sprintf("file is larger than %d bytes", options.MaxBytes)}
+	}
+
+	mediaType := detectType(filename, declared, data)
+	var text string
+	switch {
+	case mediaType == "application/pdf":
+		text, err = pdfText(data)
+	case isText(data):
+		text = string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	default:
+		err = fmt.Errorf("unsupported file type %s, only PDF and text files are supported", mediaType)
+	}
+	if err != nil {
+		return "", &Error{Filename: filename, Reason: err.Error()}
+	}
+	return render(filename, mediaType, strings.TrimSpace(text), options.MaxTokens), nil
+}
+
+// decode 返回 data URL 中声明的 MIME 类型和解码后的内容。
+func decode(fileData string) (declared string, data []byte, err error) {
+	payload := fileData
+	if header, rest, ok := strings.Cut(fileData, ","); ok && strings.HasPrefix(header, "data:") {
+		declared, _, _ = strings.Cut(strings.TrimPrefix(header, "data:"), ";")
+		payload = rest
+	}
+	if payload == "" {
+		return "", nil, fmt.Errorf("file_data is empty")
+	}
+	if data, err = base64.StdEncoding.DecodeString(payload); err != nil {
+		if data, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(payload, "=")); err != nil {
+			return "", nil, fmt.Errorf("file_data is not valid base64")
+		}
+	}
+	return declared, data, nil
+}
+
+// detectType 依次根据内容、声明的类型和扩展名确定文件类型。
+func detectType(filename, declared string, data []byte) string {
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "application/pdf"
+	}
+	if declared != "" && declared != "application/octet-stream" {
+		return declared
+	}
+	if byExtension := mime.TypeByExtension(path.Ext(filename)); byExtension != "" {
+		mediaType, _, _ := strings.Cut(byExtension, ";")
+		return mediaType
+	}
+	mediaType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	return mediaType
+}
+
+// isText 判断内容是否为 UTF-8 文本，纯文本、Markdown、CSV 和源代码都按文本处理。
+func isText(data []byte) bool {
+	return utf8.Valid(data) && !bytes.ContainsRune(data, 0)
+}
+
+// pdfText 提取 PDF 中的文本。解析库遇到损坏的文件时可能 panic，这里统一转为错误。
+func pdfText(data []byte) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to read PDF: %v", r)
+		}
+	}()
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF: %v", err)
+	}
+	var b strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		pageText, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", fmt.Errorf("failed to read PDF page %d: %v", i, err)
+		}
+		b.WriteString(pageText)
+		b.WriteString("\n\n")
+	}
+	if strings.TrimSpace(b.String()) == "" {
+		return "", fmt.Errorf("PDF contains no extractable text (scanned documents are not supported)")
+	}
+	return b.String(), nil
+}
+
+// render 用标记包裹文件内容，让上游模型能区分文件和用户的消息。
+func render(filename, mediaType, text string, maxTokens int) string {
+	var notice string
+	if maxTokens > 0 {
+		if total := util.CountToken(text); total > maxTokens {
+			text = util.TruncateToTokens(text, maxTokens)
+			notice = fmt.Sprintf("\n[Truncated: the file has about %d tokens, only the first %d are included.]", total, maxTokens)
+		}
+	}
+	var b strings.Builder
+	b.WriteString("<file")
+	if filename != "" {
+		fmt.Fprintf(&b, " name=%q", filename)
+	}
+	fmt.Fprintf(&b, " type=%q>\n", mediaType)
+	b.WriteString(text)
+	b.WriteString(notice)
+	b.WriteString("\n</file>")
+	return b.String()
+}
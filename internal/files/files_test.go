@@ -0,0 +1,93 @@
+package files
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func dataURL(mediaType, content string) string {
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString([]byte(content))
+}
+
+// minimalPDF 生成只有一页、包含一行文本的 PDF。
+func minimalPDF(text string) string {
+	stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.String()
+}
+
+func TestExtractText(t *testing.T) {
+	text, err := Extract("data.csv", dataURL("text/csv", "name,age\nalice,30\n"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "<file name=\"data.csv\" type=\"text/csv\">\nname,age\nalice,30\n</file>" {
+		t.Fatalf("unexpected text %q", text)
+	}
+
+	// 没有声明类型的源代码按内容识别为文本
+	code := base64.StdEncoding.EncodeToString([]byte("package main\n\nfunc main() {}\n"))
+	if text, err = Extract("main.go", code, Options{}); err != nil || !strings.Contains(text, "func main() {}") {
+		t.Fatalf("unexpected result %q, %v", text, err)
+	}
+}
+
+func TestExtractTruncates(t *testing.T) {
+	text, err := Extract("notes.md", dataURL("text/markdown", strings.Repeat("lorem ipsum ", 500)), Options{MaxTokens: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "[Truncated: the file has about") || len(text) > 1000 {
+		t.Fatalf("expected truncated text, got %d bytes: %q", len(text), text)
+	}
+}
+
+func TestExtractPDF(t *testing.T) {
+	text, err := Extract("report.pdf", dataURL("application/pdf", minimalPDF("Quarterly revenue grew")), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "type=\"application/pdf\"") || !strings.Contains(text, "Quarterly revenue grew") {
+		t.Fatalf("unexpected text %q", text)
+	}
+
+	var fileErr *Error
+	if _, err := Extract("broken.pdf", dataURL("application/pdf", "%PDF-1.4\ngarbage"), Options{}); !errors.As(err, &fileErr) {
+		t.Fatalf("expected *Error for a broken PDF, got %v", err)
+	}
+}
+
+func TestExtractRejects(t *testing.T) {
+	var fileErr *Error
+	for name, data := range map[string]string{
+		"binary":    dataURL("application/octet-stream", "\x00\x01\x02\xff"),
+		"too large": dataURL("text/plain", strings.Repeat("a", 100)),
+		"not b64":   "data:text/plain;base64,@@@",
+	} {
+		if _, err := Extract(name, data, Options{MaxBytes: 50}); !errors.As(err, &fileErr) {
+			t.Errorf("%s: expected *Error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// maxRedirects 是下载远程图片时允许的最大重定向次数。
+const maxRedirects = 5
+
+// errPrivateAddress 表示目标地址是回环、内网或链路本地地址。
+var errPrivateAddress = errors.New("private network addresses are not allowed")
+
+// newFetchClient 创建下载远程图片用的 HTTP 客户端。
+// 每次建立连接时检查实际连接的 IP，避免通过 DNS 解析到内网地址绕过检查；
+// 为此不使用环境变量中的代理。
+func newFetchClient(options Options) *http.Client {
+	dialer := &net.Dialer{Timeout: options.Timeout}
+	if !options.AllowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   options.Timeout,
+		ResponseHeaderTimeout: options.Timeout,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   options.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return checkHost(options, req.URL)
+		},
+	}
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// checkHost 按 DenyHosts 和 AllowHosts 检查 URL 的域名。
+func checkHost(options Options, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme %q is not allowed", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchHost(options.DenyHosts, host) {
+		return fmt.Errorf("host %s is not allowed", host)
+	}
+	if len(options.AllowHosts) > 0 && !matchHost(options.AllowHosts, host) {
+		return fmt.Errorf("host %s is not in the allow list", host)
+	}
+	return nil
+}
+
+// matchHost 判断 host 是否匹配列表中的某一项，*.example.com 匹配 example.com 的所有子域名。
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if pattern != "" && host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch 下载远程图片，超过 MaxFetchBytes 时返回错误。
+func (p *Processor) fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, invalid(rawURL, "malformed URL")
+	}
+	if err := checkHost(p.options, u); err != nil {
+		return nil, invalid(rawURL, "%v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, invalid(rawURL, "malformed URL")
+	}
+	req.Header.Set("Accept", "image/*")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, errPrivateAddress) {
+			return nil, invalid(rawURL, "%v", errPrivateAddress)
+		}
+		return nil, invalid(rawURL, "download failed: %v", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, invalid(rawURL, "download failed with status %d", resp.StatusCode)
+	}
+
+	limit := p.options.MaxFetchBytes
+	if limit > 0 && resp.ContentLength > limit {
+		return nil, invalid(rawURL, "image is larger than %d bytes", limit)
+	}
+	reader := io.Reader(resp.Body)
+	if limit > 0 {
+		reader = io.LimitReader(resp.Body, limit+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, invalid(rawURL, "download failed: %v", err)
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return nil, invalid(rawURL, "image is larger than %d bytes", limit)
+	}
+	return data, nil
+}
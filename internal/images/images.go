@@ -0,0 +1,217 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	stddraw "image/draw"
+	_ "image/gif" // 注册 GIF 解码器，只取第一帧
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// 上游只接受这两种格式，其他格式会被转换。
+var upstreamTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+}
+
+// maxPixels 限制解码的像素数，防止解压炸弹。
+const maxPixels = 50_000_000
+
+// jpegQuality 是重新编码为 JPEG 时的质量。
+const jpegQuality = 85
+
+// Options 控制图片的下载和预处理。
+type Options struct {
+	MaxFetchBytes int64         // 下载远程图片的最大字节数
+	Timeout       time.Duration // 下载远程图片的超时时间
+	AllowHosts    []string      // 非空时只允许从这些域名下载，支持 *.example.com
+	DenyHosts     []string      // 禁止下载的域名，优先于 AllowHosts
+	AllowPrivate  bool          // 是否允许下载回环、内网等地址，默认禁止以防 SSRF
+	MaxDimension  int           // 长边超过该像素数时等比缩小，0 表示不限制
+	MaxBytes      int           // 编码后超过该字节数时重新编码或继续缩小，0 表示不限制
+}
+
+// Error 表示请求中的图片无法使用，应以 400 返回给客户端。
+type Error struct {
+	URL    string
+	Code   string // invalid_image / image_not_supported
+	Reason string
+}
+
+func (e *Error) Error() string {
+	if e.URL == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("Invalid image %s: %s", shortURL(e.URL), e.Reason)
+}
+
+func invalid(url, format string, args ...any) *Error {
+	return &Error{URL: url, Code: "invalid_image", Reason: fmt.Sprintf(format, args...)}
+}
+
+// shortURL 截断错误信息中的 URL，data URL 只保留前缀。
+func shortURL(url string) string {
+	if strings.HasPrefix(url, "data:") {
+		if comma := strings.IndexByte(url, ','); comma > 0 && comma < 64 {
+			return url[:comma] + ",..."
+		}
+	}
+	if len(url) > 128 {
+		return url[:128] + "..."
+	}
+	return url
+}
+
+// Processor 把请求中的图片（data URL 或远程 URL）转换为上游可以接受的 data URL。
+type Processor struct {
+	options Options
+	client  *http.Client
+}
+
+// NewProcessor 使用给定的选项创建 Processor。
+func NewProcessor(options Options) *Processor {
+	return &Processor{options: options, client: newFetchClient(options)}
+}
+
+// Prepare 读取图片，检测真实格式，必要时缩小、转换格式或重新编码，返回 MIME 类型和 data URL。
+func (p *Processor) Prepare(ctx context.Context, url string) (mimeType, dataURL string, err error) {
+	var data []byte
+	switch {
+	case strings.HasPrefix(url, "data:"):
+		data, err = decodeDataURL(url)
+	case strings.HasPrefix(url, "http://"), strings.HasPrefix(url, "https://"):
+		data, err = p.fetch(ctx, url)
+	default:
+		return "", "", invalid(url, "only data URLs and http(s) URLs are supported")
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	mimeType = http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", "", invalid(url, "content is %s, not an image", mimeType)
+	}
+	data, mimeType, err = p.transform(data, mimeType)
+	if err != nil {
+		return "", "", invalid(url, "%v", err)
+	}
+	return mimeType, "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func decodeDataURL(url string) ([]byte, error) {
+	header, payload, ok := strings.Cut(url, ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, invalid(url, "data URL must be base64 encoded")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		// 部分客户端使用 URL 安全的 base64 或省略填充
+		if data, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(payload, "=")); err != nil {
+			return nil, invalid(url, "invalid base64 data")
+		}
+	}
+	return data, nil
+}
+
+// transform 在图片已经符合要求时原样返回，否则解码后缩小并重新编码。
+func (p *Processor) transform(data []byte, mimeType string) ([]byte, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported image format %s", mimeType)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, "", fmt.Errorf("image is too large (%dx%d)", config.Width, config.Height)
+	}
+	fitsDimension := p.options.MaxDimension <= 0 || max(config.Width, config.Height) <= p.options.MaxDimension
+	fitsBytes := p.options.MaxBytes <= 0 || len(data) <= p.options.MaxBytes
+	if upstreamTypes[mimeType] && fitsDimension && fitsBytes {
+		return data, mimeType, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s image: %v", format, err)
+	}
+	if !fitsDimension {
+		img = resize(img, p.options.MaxDimension)
+	}
+
+	// 原图是 JPEG 或没有透明通道时使用 JPEG，否则保留为 PNG
+	encoded, mimeType, err := encode(img, format != "jpeg" && !opaque(img))
+	if err != nil {
+		return nil, "", err
+	}
+	for p.options.MaxBytes > 0 && len(encoded) > p.options.MaxBytes {
+		bounds := img.Bounds()
+		longest := max(bounds.Dx(), bounds.Dy())
+		if longest <= 256 {
+			return nil, "", fmt.Errorf("image cannot be compressed below %d bytes", p.options.MaxBytes)
+		}
+		img = resize(img, longest*3/4)
+		if encoded, mimeType, err = encode(img, false); err != nil {
+			return nil, "", err
+		}
+	}
+	return encoded, mimeType, nil
+}
+
+func encode(img image.Image, keepAlpha bool) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if keepAlpha {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("failed to encode image: %v", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %v", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// resize 把图片等比缩小到长边为 longest 像素。
+func resize(img image.Image, longest int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width >= height {
+		height = max(1, height*longest/width)
+		width = longest
+	} else {
+		width = max(1, width*longest/height)
+		height = longest
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// flatten 把透明部分合成到白色背景上，JPEG 不支持透明通道。
+func flatten(img image.Image) image.Image {
+	if opaque(img) {
+		return img
+	}
+	dst := image.NewRGBA(img.Bounds())
+	stddraw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, stddraw.Src)
+	stddraw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, stddraw.Over)
+	return dst
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
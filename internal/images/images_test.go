@@ -0,0 +1,124 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeResult(t *testing.T, dataURL string) image.Config {
+	t.Helper()
+	_, payload, _ := strings.Cut(dataURL, ",")
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestPrepareDataURL(t *testing.T) {
+	processor := NewProcessor(Options{MaxDimension: 100})
+	small := "data:image/png;base64," + base64.StdEncoding.EncodeToString(encodePNG(t, 40, 20))
+	mimeType, dataURL, err := processor.Prepare(context.Background(), small)
+	if err != nil || mimeType != "image/png" || dataURL != small {
+		t.Fatalf("small PNG should pass through unchanged: %s, %v", mimeType, err)
+	}
+
+	// 声明的类型不可信，以内容为准；超出尺寸的图片按比例缩小
+	large := "data:image/webp;base64," + base64.StdEncoding.EncodeToString(encodePNG(t, 400, 200))
+	mimeType, dataURL, err = processor.Prepare(context.Background(), large)
+	if err != nil || mimeType != "image/jpeg" {
+		t.Fatalf("unexpected result %s, %v", mimeType, err)
+	}
+	if config := decodeResult(t, dataURL); config.Width != 100 || config.Height != 50 {
+		t.Fatalf("expected 100x50, got %dx%d", config.Width, config.Height)
+	}
+
+	var imageErr *Error
+	_, _, err = processor.Prepare(context.Background(), "data:image/png;base64,"+base64.StdEncoding.EncodeToString([]byte("not an image")))
+	if !errors.As(err, &imageErr) || imageErr.Code != "invalid_image" {
+		t.Fatalf("expected invalid_image error, got %v", err)
+	}
+}
+
+func TestPrepareConvertsGIF(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{G: 255, A: 255}}
+	frame := image.NewPaletted(image.Rect(0, 0, 10, 10), palette)
+	frame.SetColorIndex(5, 5, 1)
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, &gif.GIF{Image: []*image.Paletted{frame, frame}, Delay: []int{10, 10}}); err != nil {
+		t.Fatal(err)
+	}
+
+	mimeType, dataURL, err := NewProcessor(Options{}).Prepare(context.Background(), "data:image/gif;base64,"+base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if err != nil || mimeType != "image/png" || !strings.HasPrefix(dataURL, "data:image/png;base64,") {
+		t.Fatalf("expected transparent GIF to become PNG, got %s, %v", mimeType, err)
+	}
+}
+
+func TestPrepareRemoteURL(t *testing.T) {
+	data := encodePNG(t, 30, 30)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	var imageErr *Error
+	if _, _, err := NewProcessor(Options{}).Prepare(context.Background(), server.URL+"/cat.png"); !errors.As(err, &imageErr) {
+		t.Fatalf("loopback address should be rejected, got %v", err)
+	}
+	if _, _, err := NewProcessor(Options{AllowPrivate: true, DenyHosts: []string{"127.0.0.1"}}).Prepare(context.Background(), server.URL); !errors.As(err, &imageErr) {
+		t.Fatalf("denied host should be rejected, got %v", err)
+	}
+	if _, _, err := NewProcessor(Options{AllowPrivate: true, MaxFetchBytes: 10}).Prepare(context.Background(), server.URL); !errors.As(err, &imageErr) {
+		t.Fatalf("oversized download should be rejected, got %v", err)
+	}
+
+	mimeType, _, err := NewProcessor(Options{AllowPrivate: true}).Prepare(context.Background(), server.URL)
+	if err != nil || mimeType != "image/png" {
+		t.Fatalf("unexpected result %s, %v", mimeType, err)
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	patterns := []string{"images.example.com", "*.cdn.net"}
+	for host, want := range map[string]bool{
+		"images.example.com": true,
+		"example.com":        false,
+		"a.b.cdn.net":        true,
+		"cdn.net":            false,
+	} {
+		if got := matchHost(patterns, host); got != want {
+			t.Errorf("matchHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
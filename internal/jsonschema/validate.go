@@ -0,0 +1,335 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidationError 描述 JSON 值与 schema 不匹配的位置和原因，Path 使用 JSON Pointer 风格。
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Validate 校验 value（由 encoding/json 解码得到）是否满足 schema。
+// 只实现了结构化输出中常用的关键字子集：type、enum、const、properties、required、
+// additionalProperties、items、min/max 系列、pattern、anyOf/oneOf/allOf 以及本地 $ref。
+// 未识别的关键字会被忽略。
+func Validate(schema any, value any) error {
+	root, _ := schema.(map[string]any)
+	v := validator{root: root}
+	return v.validate(schema, value, "")
+}
+
+type validator struct {
+	root  map[string]any
+	depth int
+}
+
+func (v *validator) validate(schema any, value any, path string) error {
+	switch s := schema.(type) {
+	case nil:
+		return nil
+	case bool:
+		if !s {
+			return &ValidationError{Path: path, Message: "value is not allowed"}
+		}
+		return nil
+	case map[string]any:
+		return v.validateObject(s, value, path)
+	}
+	return nil
+}
+
+func (v *validator) validateObject(schema map[string]any, value any, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := v.resolve(ref)
+		if err != nil {
+			return &ValidationError{Path: path, Message: err.Error()}
+		}
+		v.depth++
+		defer func() { v.depth-- }()
+		if v.depth > 64 {
+			return &ValidationError{Path: path, Message: "schema $ref nesting is too deep"}
+		}
+		return v.validate(resolved, value, path)
+	}
+
+	if types, ok := schemaTypes(schema["type"]); ok && !matchesAnyType(types, value) {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), typeName(value))}
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		matched := false
+		for _, candidate := range enum {
+			if equalJSON(candidate, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &ValidationError{Path: path, Message: "value is not one of the allowed enum values"}
+		}
+	}
+	if constant, ok := schema["const"]; ok && !equalJSON(constant, value) {
+		return &ValidationError{Path: path, Message: "value does not match const"}
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		if err := v.validateProperties(schema, val, path); err != nil {
+			return err
+		}
+	case []any:
+		if err := v.validateItems(schema, val, path); err != nil {
+			return err
+		}
+	case string:
+		length := len([]rune(val))
+		if min, ok := number(schema["minLength"]); ok && float64(length) < min {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("string is shorter than %v", min)}
+		}
+		if max, ok := number(schema["maxLength"]); ok && float64(length) > max {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("string is longer than %v", max)}
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(val) {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("string does not match pattern %q", pattern)}
+			}
+		}
+	case float64:
+		if min, ok := number(schema["minimum"]); ok && val < min {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("number is less than %v", min)}
+		}
+		if max, ok := number(schema["maximum"]); ok && val > max {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("number is greater than %v", max)}
+		}
+		if min, ok := number(schema["exclusiveMinimum"]); ok && val <= min {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("number must be greater than %v", min)}
+		}
+		if max, ok := number(schema["exclusiveMaximum"]); ok && val >= max {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("number must be less than %v", max)}
+		}
+	}
+
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			if err := v.validate(sub, value, path); err != nil {
+				return err
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		if v.countMatches(anyOf, value, path) == 0 {
+			return &ValidationError{Path: path, Message: "value does not match any schema in anyOf"}
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		if n := v.countMatches(oneOf, value, path); n != 1 {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("value matches %d schemas in oneOf, expected exactly 1", n)}
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateProperties(schema map[string]any, value map[string]any, path string) error {
+	if required, ok := schema["required"].([]any); ok {
+		for _, key := range required {
+			if name, ok := key.(string); ok {
+				if _, present := value[name]; !present {
+					return &ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		childPath := path + "/" + key
+		if propertySchema, ok := properties[key]; ok {
+			if err := v.validate(propertySchema, value[key], childPath); err != nil {
+				return err
+			}
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("unexpected property %q", key)}
+			}
+		case map[string]any:
+			if err := v.validate(additional, value[key], childPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	if min, ok := number(schema["minProperties"]); ok && float64(len(value)) < min {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("object has fewer than %v properties", min)}
+	}
+	if max, ok := number(schema["maxProperties"]); ok && float64(len(value)) > max {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("object has more than %v properties", max)}
+	}
+	return nil
+}
+
+func (v *validator) validateItems(schema map[string]any, value []any, path string) error {
+	if min, ok := number(schema["minItems"]); ok && float64(len(value)) < min {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("array has fewer than %v items", min)}
+	}
+	if max, ok := number(schema["maxItems"]); ok && float64(len(value)) > max {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("array has more than %v items", max)}
+	}
+	if items, ok := schema["items"]; ok {
+		for i, item := range value {
+			if err := v.validate(items, item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if equalJSON(value[i], value[j]) {
+					return &ValidationError{Path: path, Message: "array items are not unique"}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) countMatches(schemas []any, value any, path string) int {
+	matches := 0
+	for _, sub := range schemas {
+		if v.validate(sub, value, path) == nil {
+			matches++
+		}
+	}
+	return matches
+}
+
+// resolve 解析指向当前文档内部的 $ref，例如 #/$defs/Item。
+func (v *validator) resolve(ref string) (any, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	var current any = v.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		node, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot resolve $ref %q", ref)
+		}
+		if current, ok = node[token]; !ok {
+			return nil, fmt.Errorf("cannot resolve $ref %q", ref)
+		}
+	}
+	return current, nil
+}
+
+func schemaTypes(raw any) ([]string, bool) {
+	switch t := raw.(type) {
+	case string:
+		return []string{t}, true
+	case []any:
+		var types []string
+		for _, element := range t {
+			if s, ok := element.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types, len(types) > 0
+	}
+	return nil, false
+}
+
+func matchesAnyType(types []string, value any) bool {
+	for _, t := range types {
+		switch t {
+		case "object":
+			if _, ok := value.(map[string]any); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]any); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := value.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func number(raw any) (float64, bool) {
+	switch n := raw.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func equalJSON(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
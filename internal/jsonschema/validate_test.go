@@ -0,0 +1,49 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func decode(t *testing.T, raw string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		t.Fatalf("invalid test JSON %q: %v", raw, err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	schema := decode(t, `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}}
+		},
+		"required": ["name", "age"],
+		"additionalProperties": false,
+		"$defs": {"tag": {"enum": ["a", "b"]}}
+	}`)
+
+	cases := []struct {
+		value string
+		valid bool
+	}{
+		{`{"name": "x", "age": 3}`, true},
+		{`{"name": "x", "age": 3, "tags": ["a", "b"]}`, true},
+		{`{"name": "x"}`, false},
+		{`{"name": "", "age": 3}`, false},
+		{`{"name": "x", "age": 3.5}`, false},
+		{`{"name": "x", "age": 3, "extra": true}`, false},
+		{`{"name": "x", "age": 3, "tags": ["c"]}`, false},
+		{`[1, 2]`, false},
+	}
+	for _, tc := range cases {
+		err := Validate(schema, decode(t, tc.value))
+		if (err == nil) != tc.valid {
+			t.Errorf("Validate(%s) error = %v, want valid=%v", tc.value, err, tc.valid)
+		}
+	}
+}
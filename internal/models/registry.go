@@ -0,0 +1,302 @@
+package models
+
+import (
+	"aurora/internal/transcript"
+	duckgotypes "aurora/typings/duckgo"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultCreated 是未配置 created 的模型在 /v1/models 中使用的时间戳。
+const DefaultCreated = 1685474247
+
+// Capabilities 描述模型支持的能力。
+type Capabilities struct {
+	Vision    bool `json:"vision"`
+	Tools     bool `json:"tools"`
+	Reasoning bool `json:"reasoning"`
+}
+
+// Defaults 是请求未指定时使用的默认参数。
+type Defaults struct {
+	MaxTokens       int      `json:"max_tokens,omitempty"`
+	Stop            []string `json:"stop,omitempty"`
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"`
+}
+
+// 请求超出上下文长度时裁剪历史消息的策略。
+const (
+	ContextDropOldest = "drop_oldest" // 从最早的一轮开始丢弃
+	ContextKeepLast   = "keep_last"   // 只保留系统提示和最近 KeepLastTurns 轮
+	ContextSummarize  = "summarize"   // 让上游总结较早的几轮，用总结代替原文
+)
+
+// defaultReasoningEfforts 是支持推理的模型在未配置 reasoning_efforts 时接受的取值。
+var defaultReasoningEfforts = []string{"none", "minimal", "low", "medium", "high"}
+
+// Model 是注册表中的一个模型。Upstream 为发送给 duck.ai 的模型名，未配置时与 ID 相同。
+type Model struct {
+	ID            string       `json:"id"`
+	Upstream      string       `json:"upstream,omitempty"`
+	Aliases       []string     `json:"aliases,omitempty"`
+	OwnedBy       string       `json:"owned_by,omitempty"`
+	Created       int64        `json:"created,omitempty"`
+	Capabilities  Capabilities `json:"capabilities"`
+	ContextLength int          `json:"context_length,omitempty"`
+	Defaults      Defaults     `json:"defaults"`
+	// ReasoningEfforts 是该模型接受的 reasoning_effort 取值，仅对支持推理的模型生效
+	ReasoningEfforts []string `json:"reasoning_efforts,omitempty"`
+	// ContextStrategy 是超出 ContextLength 时的裁剪策略，未配置时使用全局默认值
+	ContextStrategy string `json:"context_strategy,omitempty"`
+	KeepLastTurns   int    `json:"keep_last_turns,omitempty"` // keep_last 策略保留的轮数
+	// MaxFileTokens 是每个文件（PDF、文本等）注入的最大 token 数，未配置时使用全局默认值
+	MaxFileTokens int `json:"max_file_tokens,omitempty"`
+	// Template 是该模型使用的对话模板名称（模板目录中的文件名），未配置时使用默认模板
+	Template string `json:"template,omitempty"`
+	// SystemTemplate 只替换对话模板中包装系统提示的部分（数据为 transcript.SystemData），优先于 Template 中的定义
+	SystemTemplate string `json:"system_template,omitempty"`
+}
+
+// Config 是模型配置文件的格式。
+type Config struct {
+	Models []Model `json:"models"`
+}
+
+// defaultModels 是未提供配置文件时使用的内置模型列表。
+var defaultModels = []Model{
+	{
+		ID:            "gpt-4o-mini",
+		OwnedBy:       "openai",
+		Capabilities:  Capabilities{Vision: true, Tools: true},
+		ContextLength: 128000,
+	},
+	{
+		ID:            "gpt-5-mini",
+		OwnedBy:       "openai",
+		Capabilities:  Capabilities{Vision: true, Tools: true, Reasoning: true},
+		ContextLength: 400000,
+	},
+	{
+		ID:            "claude-haiku-4-5",
+		OwnedBy:       "anthropic",
+		Capabilities:  Capabilities{Vision: true, Tools: true},
+		ContextLength: 200000,
+	},
+}
+
+// NotFoundError 表示请求的模型不在注册表中。
+type NotFoundError struct {
+	Model string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Model == "" {
+		return "you must provide a model parameter"
+	}
+	return fmt.Sprintf("The model `%s` does not exist or you do not have access to it.", e.Model)
+}
+
+// Registry 保存可用的模型及其别名，线程安全。
+// 除了配置的模型，还可以包含从 duck.ai 自动发现、但没有配置的模型。
+type Registry struct {
+	mu         sync.RWMutex
+	models     []Model
+	byName     map[string]int // ID 和别名（小写）到 models 下标的映射
+	discovered []Model        // 自动发现的、不与配置重名的模型
+}
+
+// NewRegistry 使用给定的模型列表创建注册表，ID 或别名重复时返回错误。
+func NewRegistry(models []Model) (*Registry, error) {
+	r := &Registry{}
+	if err := r.set(models); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewDefaultRegistry 返回只包含内置模型的注册表。
+func NewDefaultRegistry() *Registry {
+	r, err := NewRegistry(defaultModels)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Load 从 JSON 配置文件加载注册表。
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model config: %w", err)
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse model config %s: %w", path, err)
+	}
+	if len(config.Models) == 0 {
+		return nil, fmt.Errorf("model config %s contains no models", path)
+	}
+	return NewRegistry(config.Models)
+}
+
+// normalize 为未配置的字段填充默认值。
+func (m *Model) normalize() {
+	if m.Upstream == "" {
+		m.Upstream = m.ID
+	}
+	if m.OwnedBy == "" {
+		m.OwnedBy = "duckai"
+	}
+	if m.Created == 0 {
+		m.Created = DefaultCreated
+	}
+}
+
+func (r *Registry) set(models []Model) error {
+	normalized := make([]Model, len(models))
+	byName := make(map[string]int)
+	for i, model := range models {
+		if model.ID == "" {
+			return fmt.Errorf("model #%d has no id", i)
+		}
+		if err := ValidateContextStrategy(model.ContextStrategy); err != nil {
+			return fmt.Errorf("model %q: %w", model.ID, err)
+		}
+		if model.SystemTemplate != "" {
+			if err := transcript.ValidateSystem(model.SystemTemplate); err != nil {
+				return fmt.Errorf("model %q: %w", model.ID, err)
+			}
+		}
+		model.normalize()
+		for _, name := range append([]string{model.ID}, model.Aliases...) {
+			key := strings.ToLower(name)
+			if _, exists := byName[key]; exists {
+				return fmt.Errorf("duplicate model name %q", name)
+			}
+			byName[key] = i
+		}
+		normalized[i] = model
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.models = normalized
+	r.byName = byName
+	return nil
+}
+
+// Resolve 按 ID 或别名（不区分大小写）查找模型，找不到时返回 *NotFoundError。
+func (r *Registry) Resolve(name string) (Model, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key := strings.ToLower(name)
+	if i, ok := r.byName[key]; ok {
+		return r.models[i], nil
+	}
+	for _, model := range r.discovered {
+		if strings.ToLower(model.ID) == key {
+			return model, nil
+		}
+	}
+	return Model{}, &NotFoundError{Model: name}
+}
+
+// List 返回所有模型：先是配置的模型（顺序与配置一致），然后是自动发现的模型。
+func (r *Registry) List() []Model {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := append([]Model(nil), r.models...)
+	return append(list, r.discovered...)
+}
+
+// SetDiscovered 替换自动发现的模型列表。已经配置过的模型（按 ID、别名或上游模型名匹配）会被忽略。
+func (r *Registry) SetDiscovered(discovered []Model) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	known := make(map[string]bool, len(r.byName))
+	for name := range r.byName {
+		known[name] = true
+	}
+	for _, model := range r.models {
+		known[strings.ToLower(model.Upstream)] = true
+	}
+
+	r.discovered = r.discovered[:0:0]
+	for _, model := range discovered {
+		key := strings.ToLower(model.ID)
+		if model.ID == "" || known[key] {
+			continue
+		}
+		known[key] = true
+		model.normalize()
+		r.discovered = append(r.discovered, model)
+	}
+}
+
+// MissingUpstream 返回上游模型名不在 available 中的已配置模型 ID，按配置顺序排列。
+func (r *Registry) MissingUpstream(available []string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	upstream := make(map[string]bool, len(available))
+	for _, id := range available {
+		upstream[strings.ToLower(id)] = true
+	}
+	var missing []string
+	for _, model := range r.models {
+		if !upstream[strings.ToLower(model.Upstream)] {
+			missing = append(missing, model.ID)
+		}
+	}
+	return missing
+}
+
+// Apply 把请求的模型替换为上游模型名，并补全请求未指定的默认参数。
+func (m Model) Apply(request *duckgotypes.ApiRequest) {
+	request.Model = m.Upstream
+	if request.MaxTokens == 0 {
+		request.MaxTokens = m.Defaults.MaxTokens
+	}
+	if len(request.StopSequences) == 0 {
+		request.StopSequences = m.Defaults.Stop
+	}
+	if request.ReasoningEffort == "" {
+		request.ReasoningEffort = m.Defaults.ReasoningEffort
+	}
+	if request.ReasoningEffort == "" {
+		request.ReasoningEffort = "none"
+	}
+}
+
+// ValidateReasoningEffort 检查模型是否接受给定的 reasoning_effort，空字符串表示未指定。
+func (m Model) ValidateReasoningEffort(effort string) error {
+	if effort == "" || (effort == "none" && !m.Capabilities.Reasoning) {
+		return nil
+	}
+	if !m.Capabilities.Reasoning {
+		return fmt.Errorf("The model `%s` does not support reasoning_effort.", m.ID)
+	}
+	allowed := m.ReasoningEfforts
+	if len(allowed) == 0 {
+		allowed = defaultReasoningEfforts
+	}
+	for _, value := range allowed {
+		if value == effort {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid reasoning_effort %q for model `%s`, expected one of: %s.", effort, m.ID, strings.Join(allowed, ", "))
+}
+
+// ValidateContextStrategy 检查上下文裁剪策略是否有效，空字符串表示使用默认值。
+func ValidateContextStrategy(strategy string) error {
+	switch strategy {
+	case "", ContextDropOldest, ContextKeepLast, ContextSummarize:
+		return nil
+	}
+	return fmt.Errorf("unknown context strategy %q, expected one of: %s, %s, %s", strategy, ContextDropOldest, ContextKeepLast, ContextSummarize)
+}
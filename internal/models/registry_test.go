@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistryResolve(t *testing.T) {
+	registry, err := NewRegistry([]Model{
+		{ID: "fast", Upstream: "gpt-4o-mini", Aliases: []string{"gpt-4", "GPT-3.5-Turbo"}},
+		{ID: "gpt-5-mini"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"fast", "gpt-4", "gpt-3.5-turbo"} {
+		model, err := registry.Resolve(name)
+		if err != nil || model.ID != "fast" || model.Upstream != "gpt-4o-mini" {
+			t.Fatalf("Resolve(%q) = %+v, %v", name, model, err)
+		}
+	}
+	if model, _ := registry.Resolve("gpt-5-mini"); model.Upstream != "gpt-5-mini" || model.Created != DefaultCreated {
+		t.Fatalf("defaults not applied: %+v", model)
+	}
+
+	var notFound *NotFoundError
+	if _, err := registry.Resolve("gpt-9"); !errors.As(err, &notFound) {
+		t.Fatalf("expected NotFoundError, got %v", err)
+	}
+}
+
+func TestRegistryRejectsDuplicateNames(t *testing.T) {
+	_, err := NewRegistry([]Model{
+		{ID: "a", Aliases: []string{"shared"}},
+		{ID: "b", Aliases: []string{"Shared"}},
+	})
+	if err == nil {
+		t.Fatal("expected duplicate name error")
+	}
+}
+
+func TestRegistrySetDiscovered(t *testing.T) {
+	registry, err := NewRegistry([]Model{{ID: "fast", Upstream: "gpt-4o-mini"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.SetDiscovered([]Model{{ID: "gpt-4o-mini"}, {ID: "mistral-small"}})
+
+	list := registry.List()
+	if len(list) != 2 || list[0].ID != "fast" || list[1].ID != "mistral-small" {
+		t.Fatalf("unexpected list %+v", list)
+	}
+	if _, err := registry.Resolve("mistral-small"); err != nil {
+		t.Fatalf("discovered model not resolvable: %v", err)
+	}
+
+	registry.SetDiscovered(nil)
+	if _, err := registry.Resolve("mistral-small"); err == nil {
+		t.Fatal("removed model is still resolvable")
+	}
+}
+
+func TestRegistryMissingUpstream(t *testing.T) {
+	registry, err := NewRegistry([]Model{{ID: "fast", Upstream: "gpt-4o-mini"}, {ID: "claude-3-haiku-20240307"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := registry.MissingUpstream([]string{"GPT-4o-mini", "mistral-small"})
+	if len(missing) != 1 || missing[0] != "claude-3-haiku-20240307" {
+		t.Fatalf("unexpected missing models %v", missing)
+	}
+}
+
+func TestValidateReasoningEffort(t *testing.T) {
+	plain := Model{ID: "gpt-4o-mini"}
+	reasoning := Model{ID: "gpt-5-mini", Capabilities: Capabilities{Reasoning: true}, ReasoningEfforts: []string{"low", "high"}}
+
+	cases := []struct {
+		model  Model
+		effort string
+		valid  bool
+	}{
+		{plain, "", true},
+		{plain, "none", true},
+		{plain, "high", false},
+		{reasoning, "high", true},
+		{reasoning, "medium", false},
+	}
+	for _, tc := range cases {
+		if err := tc.model.ValidateReasoningEffort(tc.effort); (err == nil) != tc.valid {
+			t.Errorf("%s/%q: error = %v, want valid=%v", tc.model.ID, tc.effort, err, tc.valid)
+		}
+	}
+}
+
+func TestRegistryRejectsInvalidSystemTemplate(t *testing.T) {
+	if _, err := NewRegistry([]Model{{ID: "custom", SystemTemplate: "### Rules\n{{range .Messages}}- {{.}}\n{{end}}"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRegistry([]Model{{ID: "bad", SystemTemplate: "{{.Missing}}"}}); err == nil {
+		t.Fatal("expected invalid system_template error")
+	}
+}
@@ -0,0 +1,90 @@
+package responses
+
+import (
+	officialtypes "aurora/typings/official"
+	"sync"
+	"time"
+)
+
+// Entry 是服务端保存的一次响应，Conversation 记录了到该响应为止的完整会话，
+// 用于在后续请求中通过 previous_response_id 延续上下文。
+type Entry struct {
+	Response     officialtypes.Response
+	Conversation officialtypes.APIRequest
+	expireAt     time.Time
+}
+
+// Store 是一个带过期时间的内存响应存储，线程安全。
+type Store struct {
+	mu         sync.Mutex
+	entries    map[string]*Entry
+	ttl        time.Duration
+	maxEntries int
+}
+
+func NewStore(ttl time.Duration, maxEntries int) *Store {
+	return &Store{
+		entries:    make(map[string]*Entry),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+// Get 返回未过期的响应，不存在时 ok 为 false。
+func (s *Store) Get(id string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		return Entry{}, false
+	}
+	if time.Now().After(entry.expireAt) {
+		delete(s.entries, id)
+		return Entry{}, false
+	}
+	return *entry, true
+}
+
+// Put 保存一次响应。超出容量时会先清理过期项，仍然超出则淘汰最早过期的一项。
+func (s *Store) Put(response officialtypes.Response, conversation officialtypes.APIRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+		s.evictLocked()
+	}
+	s.entries[response.ID] = &Entry{
+		Response:     response,
+		Conversation: conversation,
+		expireAt:     time.Now().Add(s.ttl),
+	}
+}
+
+// Delete 删除一次响应，返回该响应是否存在。
+func (s *Store) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.entries[id]
+	delete(s.entries, id)
+	return ok
+}
+
+func (s *Store) evictLocked() {
+	now := time.Now()
+	var oldestID string
+	var oldest time.Time
+	for id, entry := range s.entries {
+		if now.After(entry.expireAt) {
+			delete(s.entries, id)
+			continue
+		}
+		if oldestID == "" || entry.expireAt.Before(oldest) {
+			oldestID, oldest = id, entry.expireAt
+		}
+	}
+	if len(s.entries) >= s.maxEntries && oldestID != "" {
+		delete(s.entries, oldestID)
+	}
+}
@@ -0,0 +1,59 @@
+package toolcall
+
+import "strings"
+
+// Detector 在流式输出中查找工具调用的起始标记。
+// 标记之前的文本可以作为普通内容立即输出；可能构成标记前缀的尾部文本会被暂存；
+// 一旦出现标记，之后的所有文本都会被捕获，等待流结束后统一解析。
+type Detector struct {
+	pending  string
+	found    bool
+	captured strings.Builder
+}
+
+func NewDetector() *Detector {
+	return &Detector{}
+}
+
+// Push 追加一段文本，返回可以安全作为普通内容输出的部分。
+func (d *Detector) Push(text string) string {
+	if d.found {
+		d.captured.WriteString(text)
+		return ""
+	}
+
+	buffer := d.pending + text
+	if idx := strings.Index(buffer, OpenTag); idx >= 0 {
+		d.found = true
+		d.pending = ""
+		d.captured.WriteString(buffer[idx:])
+		return strings.TrimRight(buffer[:idx], " \t\r\n")
+	}
+
+	hold := 0
+	for n := len(OpenTag) - 1; n > 0; n-- {
+		if len(buffer) >= n && strings.HasSuffix(buffer, OpenTag[:n]) {
+			hold = n
+			break
+		}
+	}
+	d.pending = buffer[len(buffer)-hold:]
+	return buffer[:len(buffer)-hold]
+}
+
+// Found 返回是否已经出现工具调用标记。
+func (d *Detector) Found() bool {
+	return d.found
+}
+
+// Captured 返回从工具调用标记开始捕获的全部文本。
+func (d *Detector) Captured() string {
+	return d.captured.String()
+}
+
+// Flush 在流结束时返回暂存的剩余文本（仅在未出现标记时有内容）。
+func (d *Detector) Flush() string {
+	rest := d.pending
+	d.pending = ""
+	return rest
+}
@@ -0,0 +1,255 @@
+package toolcall
+
+import (
+	officialtypes "aurora/typings/official"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// 上游模型并不原生支持函数调用，网关在提示词中要求模型用以下标记包裹调用，
+// 再从输出中解析出 OpenAI 格式的 tool_calls。
+const (
+	OpenTag  = "<tool_call>"
+	CloseTag = "</tool_call>"
+)
+
+// EncodeCall 按提示词约定的 JSON 格式编码对话历史中助手发起的一次函数调用，由对话模板包裹在标记中。
+// arguments 为 JSON 字符串，不是合法 JSON 时按字符串原样放入。
+func EncodeCall(id, name, arguments string) string {
+	var args any = arguments
+	if json.Valid([]byte(arguments)) {
+		args = json.RawMessage(arguments)
+	} else if strings.TrimSpace(arguments) == "" {
+		args = map[string]any{}
+	}
+	encoded, _ := json.Marshal(struct {
+		Name      string `json:"name"`
+		Arguments any    `json:"arguments"`
+		ID        string `json:"id,omitempty"`
+	}{name, args, id})
+	return string(encoded)
+}
+
+// Call 是从模型输出中解析出的一次函数调用，Arguments 为 JSON 对象字符串。
+type Call struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// ToOpenAI 将解析结果转换为非流式响应中的 tool_calls。
+func ToOpenAI(calls []Call) []officialtypes.ToolCall {
+	toolCalls := make([]officialtypes.ToolCall, len(calls))
+	for i, call := range calls {
+		toolCalls[i] = officialtypes.ToolCall{
+			ID:   call.ID,
+			Type: "function",
+			Function: officialtypes.FunctionCall{
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			},
+		}
+	}
+	return toolCalls
+}
+
+// ParseError 表示模型输出了工具调用标记，但内容无法解析为合法的调用。
+type ParseError struct {
+	Block  string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return "malformed tool call: " + e.Reason
+}
+
+// Parse 从完整的模型输出中提取工具调用。content 为第一个调用标记之前的文本。
+// 输出中不含调用标记时 calls 为空且 err 为 nil。
+func Parse(text string, tools []officialtypes.Tool) (content string, calls []Call, err error) {
+	start := strings.Index(text, OpenTag)
+	if start < 0 {
+		return text, nil, nil
+	}
+	content = strings.TrimRight(text[:start], " \t\r\n")
+
+	rest := text[start:]
+	for {
+		open := strings.Index(rest, OpenTag)
+		if open < 0 {
+			break
+		}
+		rest = rest[open+len(OpenTag):]
+		block := rest
+		if end := strings.Index(rest, CloseTag); end >= 0 {
+			block = rest[:end]
+			rest = rest[end+len(CloseTag):]
+		} else {
+			// 缺少结束标记时，把剩余内容当作最后一个调用
+			rest = ""
+		}
+
+		call, err := parseBlock(block, tools)
+		if err != nil {
+			return content, nil, err
+		}
+		calls = append(calls, call)
+	}
+	if len(calls) == 0 {
+		return content, nil, &ParseError{Block: text[start:], Reason: "no tool call found after " + OpenTag}
+	}
+	return content, calls, nil
+}
+
+func parseBlock(block string, tools []officialtypes.Tool) (Call, error) {
+	var payload map[string]any
+	raw := strings.TrimSpace(block)
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		repaired, ok := repairJSON(raw)
+		if !ok || json.Unmarshal([]byte(repaired), &payload) != nil {
+			return Call{}, &ParseError{Block: block, Reason: "invalid JSON: " + err.Error()}
+		}
+	}
+
+	// 兼容 {"function": {"name": ..., "arguments": ...}} 形式
+	if function, ok := payload["function"].(map[string]any); ok {
+		payload = function
+	}
+	name, _ := payload["name"].(string)
+	if name == "" {
+		return Call{}, &ParseError{Block: block, Reason: "missing function name"}
+	}
+	tool, ok := findTool(tools, name)
+	if !ok {
+		return Call{}, &ParseError{Block: block, Reason: fmt.Sprintf("unknown function %q", name)}
+	}
+
+	arguments, ok := payload["arguments"]
+	if !ok {
+		arguments = payload["parameters"]
+	}
+	args, err := normalizeArguments(arguments)
+	if err != nil {
+		return Call{}, &ParseError{Block: block, Reason: err.Error()}
+	}
+	if missing := missingRequired(tool, args); len(missing) > 0 {
+		return Call{}, &ParseError{Block: block, Reason: fmt.Sprintf("function %q is missing required arguments: %s", name, strings.Join(missing, ", "))}
+	}
+
+	encoded, _ := json.Marshal(args)
+	return Call{
+		ID:        "call_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:24],
+		Name:      name,
+		Arguments: string(encoded),
+	}, nil
+}
+
+// normalizeArguments 接受 JSON 对象或 JSON 对象字符串形式的参数。
+func normalizeArguments(arguments any) (map[string]any, error) {
+	switch v := arguments.(type) {
+	case nil:
+		return map[string]any{}, nil
+	case map[string]any:
+		return v, nil
+	case string:
+		var args map[string]any
+		if strings.TrimSpace(v) == "" {
+			return map[string]any{}, nil
+		}
+		if err := json.Unmarshal([]byte(v), &args); err != nil {
+			repaired, ok := repairJSON(v)
+			if !ok || json.Unmarshal([]byte(repaired), &args) != nil {
+				return nil, errors.New("arguments is not a valid JSON object")
+			}
+		}
+		return args, nil
+	}
+	return nil, errors.New("arguments must be a JSON object")
+}
+
+func findTool(tools []officialtypes.Tool, name string) (officialtypes.Tool, bool) {
+	for _, tool := range tools {
+		if tool.Function.Name == name {
+			return tool, true
+		}
+	}
+	return officialtypes.Tool{}, false
+}
+
+func missingRequired(tool officialtypes.Tool, args map[string]any) []string {
+	schema, _ := tool.Function.Parameters.(map[string]any)
+	required, _ := schema["required"].([]any)
+	var missing []string
+	for _, key := range required {
+		if name, ok := key.(string); ok {
+			if _, present := args[name]; !present {
+				missing = append(missing, name)
+			}
+		}
+	}
+	return missing
+}
+
+var (
+	codeFencePattern     = regexp.MustCompile("(?s)^```[a-zA-Z]*\\s*(.*?)\\s*```$")
+	trailingCommaPattern = regexp.MustCompile(`,\s*([}\]])`)
+)
+
+// repairJSON 修复模型输出中常见的 JSON 问题：代码块包裹、前后多余文本、
+// 尾随逗号以及缺失的右括号。无法修复时 ok 为 false。
+func repairJSON(raw string) (string, bool) {
+	text := strings.TrimSpace(raw)
+	if m := codeFencePattern.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	}
+	start := strings.Index(text, "{")
+	if start < 0 {
+		return "", false
+	}
+	text = text[start:]
+	if end := strings.LastIndex(text, "}"); end >= 0 && json.Valid([]byte(text[:end+1])) {
+		return text[:end+1], true
+	}
+	text = trailingCommaPattern.ReplaceAllString(text, "$1")
+
+	// 补齐未闭合的字符串和括号
+	var stack []byte
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '{' || ch == '[':
+			stack = append(stack, ch)
+		case ch == '}' || ch == ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				text = text[:i+1]
+				i = len(text)
+			}
+		}
+	}
+	if inString {
+		text += `"`
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			text += "}"
+		} else {
+			text += "]"
+		}
+	}
+	return text, json.Valid([]byte(text))
+}
@@ -0,0 +1,75 @@
+package toolcall
+
+import (
+	officialtypes "aurora/typings/official"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var weatherTools = []officialtypes.Tool{
+	{
+		Type: "function",
+		Function: officialtypes.FunctionDefinition{
+			Name: "get_weather",
+			Parameters: map[string]any{
+				"type":     "object",
+				"required": []any{"city"},
+			},
+		},
+	},
+}
+
+func TestParse(t *testing.T) {
+	text := "Let me check.\n<tool_call>\n{\"name\": \"get_weather\", \"arguments\": {\"city\": \"Paris\"}}\n</tool_call>"
+	content, calls, err := Parse(text, weatherTools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "Let me check." || len(calls) != 1 {
+		t.Fatalf("unexpected parse result %q %+v", content, calls)
+	}
+	if calls[0].Name != "get_weather" || calls[0].Arguments != `{"city":"Paris"}` || !strings.HasPrefix(calls[0].ID, "call_") {
+		t.Fatalf("unexpected call %+v", calls[0])
+	}
+}
+
+func TestParseRepairsCommonMistakes(t *testing.T) {
+	text := "<tool_call>```json\n{\"function\": {\"name\": \"get_weather\", \"arguments\": \"{\\\"city\\\": \\\"Oslo\\\",}\"}}\n```</tool_call>" +
+		"<tool_call>{\"name\": \"get_weather\", \"parameters\": {\"city\": \"Rome\"}"
+	_, calls, err := Parse(text, weatherTools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0].Arguments != `{"city":"Oslo"}` || calls[1].Arguments != `{"city":"Rome"}` {
+		t.Fatalf("unexpected calls %+v", calls)
+	}
+}
+
+func TestParseRejectsInvalidCalls(t *testing.T) {
+	for _, text := range []string{
+		`<tool_call>{"name": "unknown", "arguments": {}}</tool_call>`,
+		`<tool_call>{"name": "get_weather", "arguments": {}}</tool_call>`,
+		`<tool_call>not json at all</tool_call>`,
+	} {
+		_, _, err := Parse(text, weatherTools)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("expected ParseError for %q, got %v", text, err)
+		}
+	}
+}
+
+func TestDetectorAcrossChunks(t *testing.T) {
+	detector := NewDetector()
+	var content strings.Builder
+	for _, chunk := range []string{"Sure. <to", "ol_ca", "ll>{\"name\":", "\"x\"}</tool_call>"} {
+		content.WriteString(detector.Push(chunk))
+	}
+	if !detector.Found() || content.String() != "Sure. " {
+		t.Fatalf("unexpected detector state found=%v content=%q", detector.Found(), content.String())
+	}
+	if detector.Captured() != `<tool_call>{"name":"x"}</tool_call>` {
+		t.Fatalf("unexpected captured text %q", detector.Captured())
+	}
+}
@@ -0,0 +1,147 @@
+package transcript
+
+import (
+	"aurora/logger"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileExt 是模板文件的扩展名，文件名（去掉扩展名）即模板名称。
+const fileExt = ".tmpl"
+
+// Store 保存从目录加载的模板，线程安全。目录中的 default.tmpl 会覆盖内置模板。
+type Store struct {
+	dir       string
+	mu        sync.RWMutex
+	templates map[string]*Template
+	signature string // 上次加载时目录中文件的名称、大小和修改时间
+	version   uint64 // 每次重新加载后加一
+}
+
+// NewStore 从 dir 加载模板，dir 为空时只有内置模板。初次加载失败时返回错误。
+func NewStore(dir string) (*Store, error) {
+	s := &Store{dir: dir, templates: map[string]*Template{}}
+	if dir == "" {
+		return s, nil
+	}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get 按名称查找模板，名称为空时返回默认模板。
+func (s *Store) Get(name string) (*Template, bool) {
+	if name == "" {
+		name = DefaultName
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t, ok := s.templates[name]; ok {
+		return t, true
+	}
+	if name == DefaultName {
+		return defaultTemplate, true
+	}
+	return nil, false
+}
+
+// Version 返回模板的版本号，模板重新加载后变化，可用于让基于模板的缓存失效。
+func (s *Store) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// Names 返回所有可用模板的名称。
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := []string{DefaultName}
+	for name := range s.templates {
+		if name != DefaultName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// Reload 在目录内容变化时重新加载所有模板。任何一个文件解析失败时保留原来的模板并返回错误。
+func (s *Store) Reload() (changed bool, err error) {
+	signature, paths, err := s.scan()
+	if err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	unchanged := signature == s.signature
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	templates := make(map[string]*Template, len(paths))
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read template: %w", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), fileExt)
+		if templates[name], err = Parse(name, string(source)); err != nil {
+			return false, err
+		}
+	}
+
+	s.mu.Lock()
+	s.templates = templates
+	s.signature = signature
+	s.version++
+	s.mu.Unlock()
+	return true, nil
+}
+
+func (s *Store) scan() (signature string, paths []string, err error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read template directory: %w", err)
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != fileExt {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+		paths = append(paths, filepath.Join(s.dir, entry.Name()))
+	}
+	return b.String(), paths, nil
+}
+
+// Watch 每隔 interval 检查一次模板目录，文件变化时重新加载，直到 ctx 结束。
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if s.dir == "" {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		changed, err := s.Reload()
+		if err != nil {
+			logger.Errorf("Failed to reload prompt templates, keeping the previous ones: %v", err)
+		} else if changed {
+			logger.Infof("Reloaded prompt templates: %s", strings.Join(s.Names(), ", "))
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package transcript
+
+import (
+	"aurora/internal/toolcall"
+	"aurora/logger"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// DefaultName 是内置模板的名称，模型和请求都没有指定模板时使用。
+const DefaultName = "default"
+
+//go:embed default.tmpl
+var defaultSource string
+
+var defaultTemplate = mustParseDefault()
+
+// Template 是一组控制对话如何转换为上游消息的 text/template 定义，见 default.tmpl。
+type Template struct {
+	name string
+	tmpl *template.Template
+}
+
+// SystemData 是渲染 system 部分时的数据。
+type SystemData struct {
+	System   string   // 所有 system / developer 消息，以空行分隔
+	Messages []string // 每条 system / developer 消息
+}
+
+// ToolCallData 是渲染 tool_call 部分时的数据。
+type ToolCallData struct {
+	ID        string
+	Name      string
+	Arguments string // JSON 字符串
+	JSON      string // 按提示词约定格式编码的调用对象
+}
+
+// ToolResultData 是渲染 tool_result 部分时的数据。
+type ToolResultData struct {
+	ID      string
+	Name    string
+	Content string
+}
+
+// MergeData 是渲染 merge 部分时的数据。
+type MergeData struct {
+	Role     string
+	Messages []string
+}
+
+// PrefillData 是渲染 prefill 部分时的数据。
+type PrefillData struct {
+	Text string
+}
+
+// sampleData 用于在加载时试渲染每个部分，使模板中引用了不存在字段等错误尽早暴露。
+var sampleData = map[string]any{
+	"system":      SystemData{System: "a\n\nb", Messages: []string{"a", "b"}},
+	"tool_call":   ToolCallData{ID: "call_1", Name: "f", Arguments: "{}", JSON: `{"name":"f","arguments":{}}`},
+	"tool_result": ToolResultData{ID: "call_1", Name: "f", Content: "ok"},
+	"merge":       MergeData{Role: "user", Messages: []string{"a", "b"}},
+	"prefill":     PrefillData{Text: "a"},
+}
+
+func mustParseDefault() *Template {
+	tmpl, err := template.New(DefaultName).Parse(defaultSource)
+	if err != nil {
+		panic(err)
+	}
+	return &Template{name: DefaultName, tmpl: tmpl}
+}
+
+// Default 返回内置模板。
+func Default() *Template {
+	return defaultTemplate
+}
+
+// Parse 在内置模板的基础上解析自定义模板，source 中 define 的部分覆盖内置定义，没有定义的部分沿用内置定义。
+func Parse(name, source string) (*Template, error) {
+	tmpl, err := defaultTemplate.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	// 解析到根模板上，避免文件名与 system 等部分同名时覆盖对应的定义
+	if _, err := tmpl.Parse(source); err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	t := &Template{name: name, tmpl: tmpl}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// WithSystem 返回用 source 替换 system 部分的副本，用于模型单独配置的 system_template。
+func (t *Template) WithSystem(source string) (*Template, error) {
+	system, err := template.New("system").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid system_template: %w", err)
+	}
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.AddParseTree("system", system.Tree); err != nil {
+		return nil, fmt.Errorf("invalid system_template: %w", err)
+	}
+	clone := &Template{name: t.name, tmpl: tmpl}
+	if err := clone.validate(); err != nil {
+		return nil, fmt.Errorf("invalid system_template: %w", err)
+	}
+	return clone, nil
+}
+
+// ValidateSystem 检查 system_template 能否解析和渲染。
+func ValidateSystem(source string) error {
+	_, err := defaultTemplate.WithSystem(source)
+	return err
+}
+
+// Name 返回模板的名称。
+func (t *Template) Name() string {
+	return t.name
+}
+
+func (t *Template) validate() error {
+	for section, data := range sampleData {
+		if t.tmpl.Lookup(section) == nil {
+			continue
+		}
+		if err := t.tmpl.ExecuteTemplate(io.Discard, section, data); err != nil {
+			return fmt.Errorf("template %s: %w", t.name, err)
+		}
+	}
+	// 工具提示词要求模型用 <tool_call> 标记输出调用，历史中的调用也必须是同一格式，否则会与提示词矛盾
+	var b strings.Builder
+	t.tmpl.ExecuteTemplate(&b, "tool_call", sampleData["tool_call"])
+	if call := b.String(); !strings.Contains(call, toolcall.OpenTag) || !strings.Contains(call, toolcall.CloseTag) {
+		return fmt.Errorf("template %s: tool_call must wrap the call in %s ... %s", t.name, toolcall.OpenTag, toolcall.CloseTag)
+	}
+	return nil
+}
+
+// execute 渲染 section，模板中没有定义时 ok 为 false。
+// 模板在加载时已经试渲染过，运行时仍然出错则退回内置定义。
+func (t *Template) execute(section string, data any) (text string, ok bool) {
+	if t.tmpl.Lookup(section) == nil {
+		return "", false
+	}
+	var b strings.Builder
+	if err := t.tmpl.ExecuteTemplate(&b, section, data); err != nil {
+		logger.Warnf("Template %s failed to render %s, using the built-in definition: %v", t.name, section, err)
+		if t == defaultTemplate {
+			return "", false
+		}
+		return defaultTemplate.execute(section, data)
+	}
+	return b.String(), true
+}
+
+// System 渲染系统前言。
+func (t *Template) System(messages []string) string {
+	text, _ := t.execute("system", SystemData{System: strings.Join(messages, "\n\n"), Messages: messages})
+	return text
+}
+
+// ToolCall 渲染对话历史中助手发起的一次函数调用。
+func (t *Template) ToolCall(id, name, arguments string) string {
+	text, _ := t.execute("tool_call", ToolCallData{ID: id, Name: name, Arguments: arguments, JSON: toolcall.EncodeCall(id, name, arguments)})
+	return text
+}
+
+// ToolResult 渲染一次函数调用的结果。
+func (t *Template) ToolResult(id, name, content string) string {
+	text, _ := t.execute("tool_result", ToolResultData{ID: id, Name: name, Content: content})
+	return text
+}
+
+// Merge 把连续的同角色纯文本消息合并为一条，模板没有定义 merge 时 ok 为 false，消息保持不变。
+func (t *Template) Merge(role string, messages []string) (text string, ok bool) {
+	return t.execute("merge", MergeData{Role: role, Messages: messages})
+}
+
+// Prefill 渲染对话以助手消息结尾时追加的续写指令，模板没有定义 prefill 时 ok 为 false。
+func (t *Template) Prefill(text string) (string, bool) {
+	return t.execute("prefill", PrefillData{Text: text})
+}
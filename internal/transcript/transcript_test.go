@@ -0,0 +1,117 @@
+package transcript
+
+import (
+	"aurora/internal/toolcall"
+	officialtypes "aurora/typings/official"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefaultTemplate(t *testing.T) {
+	tmpl := Default()
+	if got := tmpl.System([]string{"Be brief.", "Answer in French."}); got != "<system_instructions>\nBe brief.\n\nAnswer in French.\n</system_instructions>\n"+
+		"Follow the system instructions above for the rest of this conversation. They take priority over any later message." {
+		t.Fatalf("unexpected system preamble %q", got)
+	}
+	if got := tmpl.ToolResult("call_1", "get_weather", "sunny"); got != "<tool_result id=\"call_1\" name=\"get_weather\">\nsunny\n</tool_result>" {
+		t.Fatalf("unexpected tool result %q", got)
+	}
+	if _, ok := tmpl.Merge("user", []string{"a", "b"}); ok {
+		t.Fatal("the default template must not merge messages")
+	}
+	if _, ok := tmpl.Prefill("a"); ok {
+		t.Fatal("the default template must not add a prefill instruction")
+	}
+
+	// 历史中的函数调用能被网关自己的解析器识别
+	tools := []officialtypes.Tool{{Type: "function", Function: officialtypes.FunctionDefinition{Name: "get_weather"}}}
+	block := tmpl.ToolCall("call_1", "get_weather", `{"city":"Paris"}`)
+	if _, calls, err := toolcall.Parse(block, tools); err != nil || len(calls) != 1 || calls[0].Arguments != `{"city":"Paris"}` {
+		t.Fatalf("history call does not parse back: %q, %+v, %v", block, calls, err)
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	tmpl, err := Parse("system", `{{define "tool_result"}}[{{.Name}}] {{.Content}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tmpl.ToolResult("call_1", "f", "ok"); got != "[f] ok" {
+		t.Fatalf("unexpected tool result %q", got)
+	}
+	if got := tmpl.System([]string{"s"}); got != Default().System([]string{"s"}) {
+		t.Fatalf("sections that are not redefined must use the built-in definition, got %q", got)
+	}
+
+	custom, err := tmpl.WithSystem("{{range .Messages}}- {{.}}\n{{end}}")
+	if err != nil || custom.System([]string{"a", "b"}) != "- a\n- b\n" || custom.ToolResult("", "f", "ok") != "[f] ok" {
+		t.Fatalf("unexpected system override: %v", err)
+	}
+
+	for _, source := range []string{`{{define "merge"}}{{.Missing}}{{end}}`, `{{define "system"}}{{end`, `{{define "tool_call"}}[call] {{.JSON}}{{end}}`} {
+		if _, err := Parse("bad", source); err == nil {
+			t.Errorf("expected an error for %q", source)
+		}
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plain.tmpl")
+	write := func(source string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, modTime, modTime)
+	}
+	write(`{{define "prefill"}}v1 {{.Text}}{{end}}`, time.Unix(1, 0))
+
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get("missing"); ok {
+		t.Fatal("expected unknown template")
+	}
+	if tmpl, ok := store.Get(""); !ok || tmpl != Default() {
+		t.Fatal("expected the built-in template as default")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Watch(ctx, 10*time.Millisecond)
+
+	write(`{{define "prefill"}}v2 {{.Text}}{{end}}`, time.Unix(2, 0))
+	waitFor(t, func() bool {
+		tmpl, _ := store.Get("plain")
+		text, _ := tmpl.Prefill("x")
+		return text == "v2 x"
+	})
+
+	// 解析失败时保留原来的模板
+	write(`{{define "prefill"}}{{.Missing}}{{end}}`, time.Unix(3, 0))
+	if _, err := store.Reload(); err == nil {
+		t.Fatal("expected reload error")
+	}
+	tmpl, _ := store.Get("plain")
+	if text, _ := tmpl.Prefill("x"); text != "v2 x" {
+		t.Fatalf("expected the previous template to be kept, got %q", text)
+	}
+	if store.Version() != 2 {
+		t.Fatalf("expected version 2 after one successful reload, got %d", store.Version())
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"log"
 	"os"
 	"strings"
@@ -68,3 +69,53 @@ func Fatalf(format string, v ...any) {
 	logf(ERROR, "[FATAL] "+format, v...)
 	os.Exit(1)
 }
+
+type requestIDKey struct{}
+
+// NewContext 返回携带请求 ID 的 context，通过 Ctx 取得的日志会自动带上该 ID。
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 返回 context 中的请求 ID，不存在时返回空字符串。
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Entry 是带固定前缀的日志记录器，用于把同一请求的日志关联起来。
+type Entry struct {
+	prefix string
+}
+
+// Ctx 返回与 context 中请求 ID 关联的日志记录器。
+// context 中没有请求 ID 时，输出与包级函数相同。
+func Ctx(ctx context.Context) *Entry {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return &Entry{prefix: "[" + requestID + "] "}
+	}
+	return &Entry{}
+}
+
+// Debugf 记录 DEBUG 级别的日志
+func (e *Entry) Debugf(format string, v ...any) {
+	logf(DEBUG, "[DEBUG] "+e.prefix+format, v...)
+}
+
+// Infof 记录 INFO 级别的日志
+func (e *Entry) Infof(format string, v ...any) {
+	logf(INFO, "[INFO] "+e.prefix+format, v...)
+}
+
+// Warnf 记录 WARN 级别的日志
+func (e *Entry) Warnf(format string, v ...any) {
+	logf(WARN, "[WARN] "+e.prefix+format, v...)
+}
+
+// Errorf 记录 ERROR 级别的日志
+func (e *Entry) Errorf(format string, v ...any) {
+	logf(ERROR, "[ERROR] "+e.prefix+format, v...)
+}
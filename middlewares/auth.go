@@ -10,6 +10,10 @@ func Authorization(c *gin.Context) {
 	customer_key := os.Getenv("Authorization")
 	if customer_key != "" {
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			// Anthropic SDK 通过 x-api-key 传递密钥
+			authHeader = c.GetHeader("x-api-key")
+		}
 		if authHeader == "" {
 			c.JSON(401, gin.H{"error": "Unauthorized"})
 			c.Abort()
@@ -29,3 +33,18 @@ func Authorization(c *gin.Context) {
 	}
 	c.Next()
 }
+
+// GeminiAuthorization 只用于 Gemini 路由：Gemini 客户端通过 x-goog-api-key 或 ?key= 传递密钥，
+// 转换为 Authorization 头后再交给 Authorization 校验。其他路由不接受查询参数中的密钥。
+func GeminiAuthorization(c *gin.Context) {
+	if c.GetHeader("Authorization") == "" && c.GetHeader("x-api-key") == "" {
+		key := c.GetHeader("x-goog-api-key")
+		if key == "" {
+			key = c.Query("key")
+		}
+		if key != "" {
+			c.Request.Header.Set("Authorization", key)
+		}
+	}
+	Authorization(c)
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"aurora/logger"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 是用于关联请求日志的响应头。
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey 是请求 ID 在 gin.Context 中的键名。
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength 限制客户端传入的请求 ID 长度，避免日志被超长值污染。
+const maxRequestIDLength = 128
+
+// RequestID 为每个请求分配请求 ID：优先沿用客户端传入的 X-Request-ID，否则生成一个新的。
+// ID 会写回响应头，并存入请求的 context，供 logger.Ctx 在日志中输出。
+func RequestID(c *gin.Context) {
+	requestID := c.GetHeader(RequestIDHeader)
+	if !validRequestID(requestID) {
+		requestID = "req_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	}
+	c.Set(RequestIDKey, requestID)
+	c.Header(RequestIDHeader, requestID)
+	c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), requestID))
+	c.Next()
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
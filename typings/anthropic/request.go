@@ -0,0 +1,37 @@
+package anthropic
+
+// MessagesRequest 是 Anthropic Messages API (/v1/messages) 的请求体。
+type MessagesRequest struct {
+	Model         string    `json:"model"`
+	Messages      []Message `json:"messages"`
+	System        any       `json:"system,omitempty"` // string 或 []ContentBlock
+	MaxTokens     int       `json:"max_tokens"`
+	StopSequences []string  `json:"stop_sequences,omitempty"`
+	Stream        bool      `json:"stream"`
+	Temperature   *float64  `json:"temperature,omitempty"`
+	TopP          *float64  `json:"top_p,omitempty"`
+	TopK          *int      `json:"top_k,omitempty"`
+	Metadata      any       `json:"metadata,omitempty"`
+}
+
+// Message 的 Content 可以是 string 或 []ContentBlock 形式的数组。
+type Message struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// ContentBlock 是请求中的内容块，目前支持 text、image 和 document 三种类型。
+type ContentBlock struct {
+	Type   string       `json:"type"`
+	Text   string       `json:"text,omitempty"`
+	Source *ImageSource `json:"source,omitempty"`
+	Title  string       `json:"title,omitempty"` // document 的标题
+}
+
+// ImageSource 描述图片或文档的来源，type 为 base64 或 url；document 还可以是 text，此时内容在 Data 中。
+type ImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
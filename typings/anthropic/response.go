@@ -0,0 +1,114 @@
+package anthropic
+
+import "encoding/json"
+
+// Message 响应中的 stop_reason 取值。
+const (
+	StopReasonEndTurn      = "end_turn"
+	StopReasonMaxTokens    = "max_tokens"
+	StopReasonStopSequence = "stop_sequence"
+)
+
+type MessagesResponse struct {
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	Role         string          `json:"role"`
+	Content      []ResponseBlock `json:"content"`
+	Model        string          `json:"model"`
+	StopReason   *string         `json:"stop_reason"`
+	StopSequence *string         `json:"stop_sequence"`
+	Usage        Usage           `json:"usage"`
+}
+
+type ResponseBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func NewMessagesResponse(id string, model string, text string) MessagesResponse {
+	return MessagesResponse{
+		ID:      id,
+		Type:    "message",
+		Role:    "assistant",
+		Content: []ResponseBlock{{Type: "text", Text: text}},
+		Model:   model,
+	}
+}
+
+// StreamEvent 是流式响应中的一个 SSE 事件，Type 同时作为 event 名称。
+type StreamEvent struct {
+	Type         string            `json:"type"`
+	Message      *MessagesResponse `json:"message,omitempty"`
+	Index        *int              `json:"index,omitempty"`
+	ContentBlock *ResponseBlock    `json:"content_block,omitempty"`
+	Delta        any               `json:"delta,omitempty"`
+	Usage        *Usage            `json:"usage,omitempty"`
+	Error        *ErrorDetail      `json:"error,omitempty"`
+}
+
+func (e *StreamEvent) String() string {
+	resp, _ := json.Marshal(e)
+	return string(resp)
+}
+
+type TextDelta struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type MessageDelta struct {
+	StopReason   string  `json:"stop_reason"`
+	StopSequence *string `json:"stop_sequence"`
+}
+
+func NewMessageStartEvent(id string, model string, inputTokens int) StreamEvent {
+	message := NewMessagesResponse(id, model, "")
+	message.Content = []ResponseBlock{}
+	message.Usage = Usage{InputTokens: inputTokens}
+	return StreamEvent{Type: "message_start", Message: &message}
+}
+
+func NewContentBlockStartEvent(index int) StreamEvent {
+	return StreamEvent{Type: "content_block_start", Index: &index, ContentBlock: &ResponseBlock{Type: "text"}}
+}
+
+func NewContentBlockDeltaEvent(index int, text string) StreamEvent {
+	return StreamEvent{Type: "content_block_delta", Index: &index, Delta: TextDelta{Type: "text_delta", Text: text}}
+}
+
+func NewContentBlockStopEvent(index int) StreamEvent {
+	return StreamEvent{Type: "content_block_stop", Index: &index}
+}
+
+func NewMessageDeltaEvent(stopReason string, stopSequence *string, usage Usage) StreamEvent {
+	return StreamEvent{Type: "message_delta", Delta: MessageDelta{StopReason: stopReason, StopSequence: stopSequence}, Usage: &usage}
+}
+
+func NewMessageStopEvent() StreamEvent {
+	return StreamEvent{Type: "message_stop"}
+}
+
+// ErrorResponse 是 Anthropic 风格的错误响应。
+type ErrorResponse struct {
+	Type  string      `json:"type"`
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func NewErrorResponse(errorType string, message string) ErrorResponse {
+	return ErrorResponse{Type: "error", Error: ErrorDetail{Type: errorType, Message: message}}
+}
+
+// NewErrorEvent 返回流式响应中途出错时发送的 error 事件。
+func NewErrorEvent(errorType string, message string) StreamEvent {
+	return StreamEvent{Type: "error", Error: &ErrorDetail{Type: errorType, Message: message}}
+}
@@ -8,6 +8,12 @@ type ApiRequest struct {
 	Metadata             Metadata       `json:"metadata"`
 	ReasoningEffort      string         `json:"reasoningEffort"`
 	DurableStream        *DurableStream `json:"durableStream,omitempty"`
+
+	// 以下字段只在网关内部使用，不会发送到上游。
+	StopSequences []string `json:"-"` // 网关侧截断输出的停止序列
+	MaxTokens     int      `json:"-"` // 网关侧截断输出的 token 上限，0 表示不限制
+	// 开头必须保留的消息数（系统提示、工具说明等），裁剪上下文时不会被丢弃
+	PinnedMessages int `json:"-"`
 }
 
 type messages struct {
@@ -64,6 +70,14 @@ type PartImage struct {
 	Image    string `json:"image"`
 }
 
+// PartFile 是请求中的文件（PDF、文本、代码等），只在网关内部使用，
+// 发送到上游之前会被替换为提取出的文本。
+type PartFile struct {
+	Type     string `json:"type"`
+	Filename string `json:"filename,omitempty"`
+	Data     string `json:"-"` // data URL 或 base64
+}
+
 func (a *ApiRequest) AddMessage(role string, content any) {
 	a.Messages = append(a.Messages, messages{
 		Role:    role,
@@ -78,6 +92,14 @@ func (a *ApiRequest) AddMessageUser(content any) {
 	})
 }
 
+// PrependMessageUser 在消息列表开头插入一条用户消息。
+func (a *ApiRequest) PrependMessageUser(content any) {
+	a.Messages = append([]any{MessageUser{
+		Role:    "user",
+		Content: content,
+	}}, a.Messages...)
+}
+
 func (a *ApiRequest) AddMessageAssistant(parts []any) {
 	a.Messages = append(a.Messages, MessageAssistant{
 		Role:    "assistant",
@@ -6,4 +6,9 @@ type ApiResponse struct {
 	Id      string `json:"id"`
 	Action  string `json:"action"`
 	Model   string `json:"model"`
+	// 推理模型的思考内容，部分模型也会以 action 为 reasoning 的消息发送
+	Reasoning string `json:"reasoning,omitempty"`
+	// action 为 error 时上游给出的状态码和错误类型，例如 429 / ERR_CONVERSATION_LIMIT
+	Status int    `json:"status,omitempty"`
+	Type   string `json:"type,omitempty"`
 }
@@ -0,0 +1,33 @@
+package gemini
+
+// GenerateContentRequest 是 Gemini generateContent / streamGenerateContent 的请求体。
+type GenerateContentRequest struct {
+	Contents          []Content         `json:"contents"`
+	SystemInstruction *Content          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// Content 中的 Role 为 user 或 model。
+type Content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []Part `json:"parts"`
+}
+
+type Part struct {
+	Text       string `json:"text,omitempty"`
+	InlineData *Blob  `json:"inlineData,omitempty"`
+}
+
+// Blob 是以 base64 编码内联的二进制数据，例如图片。
+type Blob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type GenerationConfig struct {
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	TopK            *int     `json:"topK,omitempty"`
+}
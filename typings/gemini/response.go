@@ -0,0 +1,76 @@
+package gemini
+
+import "encoding/json"
+
+// Candidate 的 finishReason 取值。
+const (
+	FinishReasonStop      = "STOP"
+	FinishReasonMaxTokens = "MAX_TOKENS"
+)
+
+type GenerateContentResponse struct {
+	Candidates    []Candidate    `json:"candidates"`
+	UsageMetadata *UsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string         `json:"modelVersion,omitempty"`
+}
+
+func (r *GenerateContentResponse) String() string {
+	resp, _ := json.Marshal(r)
+	return string(resp)
+}
+
+type Candidate struct {
+	Content      Content `json:"content"`
+	FinishReason string  `json:"finishReason,omitempty"`
+	Index        int     `json:"index"`
+}
+
+type UsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+func NewUsageMetadata(promptTokens, candidatesTokens int) *UsageMetadata {
+	return &UsageMetadata{
+		PromptTokenCount:     promptTokens,
+		CandidatesTokenCount: candidatesTokens,
+		TotalTokenCount:      promptTokens + candidatesTokens,
+	}
+}
+
+func NewGenerateContentResponse(text string, finishReason string, model string) GenerateContentResponse {
+	return GenerateContentResponse{
+		Candidates: []Candidate{
+			{
+				Content: Content{
+					Role:  "model",
+					Parts: []Part{{Text: text}},
+				},
+				FinishReason: finishReason,
+				Index:        0,
+			},
+		},
+		ModelVersion: model,
+	}
+}
+
+// ErrorResponse 是 Google API 风格的错误响应。
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func NewErrorResponse(code int, status string, message string) ErrorResponse {
+	return ErrorResponse{Error: ErrorDetail{Code: code, Message: message, Status: status}}
+}
+
+func (e *ErrorResponse) String() string {
+	resp, _ := json.Marshal(e)
+	return string(resp)
+}
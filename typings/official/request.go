@@ -1,15 +1,129 @@
 package official
 
 type APIRequest struct {
-	Messages  []api_message `json:"messages"`
-	Stream    bool          `json:"stream"`
-	Model     string        `json:"model"`
-	PluginIDs []string      `json:"plugin_ids"`
+	Messages          []api_message   `json:"messages"`
+	Stream            bool            `json:"stream"`
+	StreamOptions     *StreamOptions  `json:"stream_options,omitempty"`
+	Model             string          `json:"model"`
+	PluginIDs         []string        `json:"plugin_ids"`
+	Tools             []Tool          `json:"tools,omitempty"`
+	ToolChoice        any             `json:"tool_choice,omitempty"` // "none" / "auto" / "required" 或指定函数
+	ParallelToolCalls *bool           `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat    *ResponseFormat `json:"response_format,omitempty"`
+
+	N                   int `json:"n,omitempty"`    // 生成的 choice 数量，默认为 1
+	Stop                any `json:"stop,omitempty"` // string 或 []string
+	MaxTokens           int `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+
+	ReasoningEffort string `json:"reasoning_effort,omitempty"` // none / minimal / low / medium / high
+
+	WebSearchOptions *WebSearchOptions `json:"web_search_options,omitempty"`
+}
+
+// WebSearchOptions 启用 duck.ai 的搜索工具，user_location 会作为近似位置传给上游。
+type WebSearchOptions struct {
+	// SearchContextSize 只校验取值（low / medium / high），duck.ai 没有对应的设置，因此不影响上游请求
+	SearchContextSize string        `json:"search_context_size,omitempty"`
+	UserLocation      *UserLocation `json:"user_location,omitempty"`
+}
+
+type UserLocation struct {
+	Type        string               `json:"type"` // 目前只有 approximate
+	Approximate *ApproximateLocation `json:"approximate,omitempty"`
+}
+
+type ApproximateLocation struct {
+	Country  string `json:"country,omitempty"`
+	Region   string `json:"region,omitempty"`
+	City     string `json:"city,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// TokenLimit 返回输出 token 上限，max_completion_tokens 优先于已废弃的 max_tokens，0 表示不限制。
+func (r *APIRequest) TokenLimit() int {
+	if r.MaxCompletionTokens > 0 {
+		return r.MaxCompletionTokens
+	}
+	if r.MaxTokens > 0 {
+		return r.MaxTokens
+	}
+	return 0
+}
+
+// ResponseFormat 的 Type 为 text、json_object 或 json_schema。
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+type JSONSchemaFormat struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      any    `json:"schema,omitempty"`
+	Strict      *bool  `json:"strict,omitempty"`
+}
+
+// JSONMode 返回是否要求模型输出 JSON（json_object 或 json_schema）。
+func (r *APIRequest) JSONMode() bool {
+	return r.ResponseFormat != nil && (r.ResponseFormat.Type == "json_object" || r.ResponseFormat.Type == "json_schema")
+}
+
+// JSONSchema 返回 json_schema 模式下的 schema，其他模式返回 nil。
+func (r *APIRequest) JSONSchema() any {
+	if r.ResponseFormat == nil || r.ResponseFormat.Type != "json_schema" || r.ResponseFormat.JSONSchema == nil {
+		return nil
+	}
+	return r.ResponseFormat.JSONSchema.Schema
+}
+
+// Tool 是 OpenAI 格式的工具定义，目前只支持 function 类型。
+type Tool struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+type FunctionDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"` // JSON Schema
+	Strict      *bool  `json:"strict,omitempty"`
+}
+
+// ToolsEnabled 返回本次请求是否需要启用工具调用。
+func (r *APIRequest) ToolsEnabled() bool {
+	return len(r.Tools) > 0 && r.ToolChoice != "none"
+}
+
+// ForcedToolName 返回 tool_choice 指定必须调用的函数名，未指定时返回空字符串。
+func (r *APIRequest) ForcedToolName() string {
+	choice, ok := r.ToolChoice.(map[string]any)
+	if !ok {
+		return ""
+	}
+	function, _ := choice["function"].(map[string]any)
+	name, _ := function["name"].(string)
+	return name
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// IncludeUsage 返回流式响应是否需要在最后附带用量 chunk。
+func (r *APIRequest) IncludeUsage() bool {
+	return r.StreamOptions != nil && r.StreamOptions.IncludeUsage
 }
 
 type api_message struct {
 	Role    string `json:"role"`
 	Content any    `json:"content"`
+	Name    string `json:"name,omitempty"`
+	// 助手发起的函数调用，此时 content 可以为 null
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool 消息对应的调用 ID
+	// 已废弃的 functions 接口中助手发起的调用
+	FunctionCall *FunctionCall `json:"function_call,omitempty"`
 }
 
 type OpenAISessionToken struct {
@@ -19,3 +133,42 @@ type OpenAISessionToken struct {
 type OpenAIRefreshToken struct {
 	RefreshToken string `json:"refresh_token"`
 }
+
+func (r *APIRequest) AddMessage(role string, content any) {
+	r.Messages = append(r.Messages, api_message{
+		Role:    role,
+		Content: content,
+	})
+}
+
+// CompletionRequest 是旧版文本补全接口 (/v1/completions) 的请求体。
+type CompletionRequest struct {
+	Model     string `json:"model"`
+	Prompt    any    `json:"prompt"` // string 或 []string
+	Suffix    string `json:"suffix,omitempty"`
+	Echo      bool   `json:"echo"`
+	Stop      any    `json:"stop,omitempty"` // string 或 []string
+	MaxTokens int    `json:"max_tokens,omitempty"`
+	Stream    bool   `json:"stream"`
+
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// Prompts 将 prompt 展开为字符串列表，数组中的每个 prompt 各对应一个 choice。
+// 不支持 token 数组形式的 prompt，此时 ok 为 false。
+func (r *CompletionRequest) Prompts() (prompts []string, ok bool) {
+	switch prompt := r.Prompt.(type) {
+	case string:
+		return []string{prompt}, true
+	case []any:
+		for _, element := range prompt {
+			text, isString := element.(string)
+			if !isString {
+				return nil, false
+			}
+			prompts = append(prompts, text)
+		}
+		return prompts, len(prompts) > 0
+	}
+	return nil, false
+}
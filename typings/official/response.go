@@ -2,12 +2,25 @@ package official
 
 import "encoding/json"
 
+// SystemFingerprint 标识生成响应的后端配置，随所有 chat.completion 响应返回。
+const SystemFingerprint = "fp_duck2api"
+
+// ResponseMeta 是同一次响应中所有 chunk 共用的 ID、创建时间和模型，
+// 保证客户端能够按 ID 对同一响应的 chunk 去重和关联。
+type ResponseMeta struct {
+	ID      string
+	Created int64
+	Model   string
+}
+
 type ChatCompletionChunk struct {
-	ID      string    `json:"id"`
-	Object  string    `json:"object"`
-	Created int64     `json:"created"`
-	Model   string    `json:"model"`
-	Choices []Choices `json:"choices"`
+	ID                string    `json:"id"`
+	Object            string    `json:"object"`
+	Created           int64     `json:"created"`
+	Model             string    `json:"model"`
+	SystemFingerprint string    `json:"system_fingerprint"`
+	Choices           []Choices `json:"choices"`
+	Usage             *Usage    `json:"usage,omitempty"`
 }
 
 func (chunk *ChatCompletionChunk) String() string {
@@ -16,146 +29,240 @@ func (chunk *ChatCompletionChunk) String() string {
 }
 
 type Choices struct {
-	Delta        Delta       `json:"delta"`
-	Index        int         `json:"index"`
-	FinishReason any 		 `json:"finish_reason"`
+	Delta        Delta `json:"delta"`
+	Index        int   `json:"index"`
+	FinishReason any   `json:"finish_reason"`
 }
 
 type Delta struct {
-	Content string `json:"content,omitempty"`
-	Role    string `json:"role,omitempty"`
+	Content          string       `json:"content,omitempty"`
+	ReasoningContent string       `json:"reasoning_content,omitempty"`
+	Role             string       `json:"role,omitempty"`
+	ToolCalls        []ToolCall   `json:"tool_calls,omitempty"`
+	Annotations      []Annotation `json:"annotations,omitempty"`
+}
+
+// Annotation 是消息中引用的来源，目前只有 url_citation 一种。
+type Annotation struct {
+	Type        string      `json:"type"`
+	URLCitation URLCitation `json:"url_citation"`
+}
+
+type URLCitation struct {
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+	StartIndex *int   `json:"start_index,omitempty"`
+	EndIndex   *int   `json:"end_index,omitempty"`
+}
+
+// ToolCall 是模型发起的一次函数调用。流式输出时 Index 标识调用序号，
+// 同一调用的 arguments 会被拆分到多个 chunk 中。
+type ToolCall struct {
+	Index    *int         `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
 }
 
-func NewChatCompletionChunk(text string) ChatCompletionChunk {
+func newChunk(meta ResponseMeta, choices []Choices) ChatCompletionChunk {
 	return ChatCompletionChunk{
-		ID:      "chatcmpl-QXlha2FBbmROaXhpZUFyZUF3ZXNvbWUK",
-		Object:  "chat.completion.chunk",
-		Created: 0,
-		Model:   "gpt-4o-mini",
-		Choices: []Choices{
-			{
-				Index: 0,
-				Delta: Delta{
-					Content: text,
-				},
-				FinishReason: nil,
+		ID:                meta.ID,
+		Object:            "chat.completion.chunk",
+		Created:           meta.Created,
+		Model:             meta.Model,
+		SystemFingerprint: SystemFingerprint,
+		Choices:           choices,
+	}
+}
+
+func NewChatCompletionChunk(meta ResponseMeta, text string) ChatCompletionChunk {
+	return newChunk(meta, []Choices{
+		{
+			Index: 0,
+			Delta: Delta{
+				Content: text,
 			},
+			FinishReason: nil,
 		},
-	}
+	})
 }
 
-func NewChatCompletionChunkWithModel(text string, model string) ChatCompletionChunk {
-	return ChatCompletionChunk{
-		ID:      "chatcmpl-QXlha2FBbmROaXhpZUFyZUF3ZXNvbWUK",
-		Object:  "chat.completion.chunk",
-		Created: 0,
-		Model:   model,
-		Choices: []Choices{
-			{
-				Index: 0,
-				Delta: Delta{
-					Content: text,
-				},
-				FinishReason: nil,
+// ReasoningChunk 返回携带推理内容片段的 chunk。
+func ReasoningChunk(meta ResponseMeta, reasoning string) ChatCompletionChunk {
+	return newChunk(meta, []Choices{
+		{
+			Index: 0,
+			Delta: Delta{
+				ReasoningContent: reasoning,
 			},
+			FinishReason: nil,
 		},
-	}
+	})
 }
 
-func StopChunkWithModel(reason string, model string) ChatCompletionChunk {
-	return ChatCompletionChunk{
-		ID:      "chatcmpl-QXlha2FBbmROaXhpZUFyZUF3ZXNvbWUK",
-		Object:  "chat.completion.chunk",
-		Created: 0,
-		Model:   model,
-		Choices: []Choices{
-			{
-				Index:        0,
-				FinishReason: reason,
+// AnnotationsChunk 返回携带引用来源的 chunk，在正文输出完毕后发送。
+func AnnotationsChunk(meta ResponseMeta, annotations []Annotation) ChatCompletionChunk {
+	return newChunk(meta, []Choices{
+		{
+			Index: 0,
+			Delta: Delta{
+				Annotations: annotations,
 			},
+			FinishReason: nil,
 		},
-	}
+	})
 }
 
-func StopChunk(reason string) ChatCompletionChunk {
-	return ChatCompletionChunk{
-		ID:      "chatcmpl-QXlha2FBbmROaXhpZUFyZUF3ZXNvbWUK",
-		Object:  "chat.completion.chunk",
-		Created: 0,
-		Model:   "gpt-4o-mini",
-		Choices: []Choices{
-			{
-				Index:        0,
-				FinishReason: reason,
+// ToolCallChunk 返回携带工具调用片段的 chunk。
+func ToolCallChunk(meta ResponseMeta, toolCalls []ToolCall) ChatCompletionChunk {
+	return newChunk(meta, []Choices{
+		{
+			Index: 0,
+			Delta: Delta{
+				ToolCalls: toolCalls,
 			},
+			FinishReason: nil,
 		},
-	}
+	})
+}
+
+func StopChunk(meta ResponseMeta, reason string) ChatCompletionChunk {
+	return newChunk(meta, []Choices{
+		{
+			Index:        0,
+			FinishReason: reason,
+		},
+	})
+}
+
+// UsageChunk 返回 stream_options.include_usage 时最后发送的用量 chunk，其 choices 为空。
+func UsageChunk(meta ResponseMeta, usage Usage) ChatCompletionChunk {
+	chunk := newChunk(meta, []Choices{})
+	chunk.Usage = &usage
+	return chunk
 }
 
 type ChatCompletion struct {
-	ID      string   `json:"id"`
-	Object  string   `json:"object"`
-	Created int64    `json:"created"`
-	Model   string   `json:"model"`
-	Usage   usage    `json:"usage"`
-	Choices []Choice `json:"choices"`
+	ID                string   `json:"id"`
+	Object            string   `json:"object"`
+	Created           int64    `json:"created"`
+	Model             string   `json:"model"`
+	SystemFingerprint string   `json:"system_fingerprint"`
+	Usage             Usage    `json:"usage"`
+	Choices           []Choice `json:"choices"`
 }
+
+// Msg 的 Content 在只有工具调用时为 null。
 type Msg struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role             string       `json:"role"`
+	Content          any          `json:"content"`
+	ReasoningContent string       `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall   `json:"tool_calls,omitempty"`
+	Annotations      []Annotation `json:"annotations,omitempty"`
 }
 type Choice struct {
-	Index        int         `json:"index"`
-	Message      Msg         `json:"message"`
+	Index        int `json:"index"`
+	Message      Msg `json:"message"`
 	FinishReason any `json:"finish_reason"`
 }
-type usage struct {
+type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
 }
 
-func NewChatCompletionWithModel(text string, model string) ChatCompletion {
+func NewUsage(promptTokens, completionTokens int) Usage {
+	return Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
+
+// NewChatCompletion 返回非流式响应，finishReason 为 stop 或 length。
+func NewChatCompletion(meta ResponseMeta, text string, finishReason string, usage Usage) ChatCompletion {
 	return ChatCompletion{
-		ID:      "chatcmpl-QXlha2FBbmROaXhpZUFyZUF3ZXNvbWUK",
-		Object:  "chat.completion",
-		Created: int64(0),
-		Model:   model,
-		Usage: usage{
-			PromptTokens:     0,
-			CompletionTokens: 0,
-			TotalTokens:      0,
-		},
+		ID:                meta.ID,
+		Object:            "chat.completion",
+		Created:           meta.Created,
+		Model:             meta.Model,
+		SystemFingerprint: SystemFingerprint,
+		Usage:             usage,
 		Choices: []Choice{
 			{
 				Message: Msg{
 					Content: text,
 					Role:    "assistant",
 				},
-				Index: 0,
+				Index:        0,
+				FinishReason: finishReason,
 			},
 		},
 	}
 }
 
-func NewChatCompletion(full_test string, input_tokens, output_tokens int) ChatCompletion {
-	return ChatCompletion{
-		ID:      "chatcmpl-QXlha2FBbmROaXhpZUFyZUF3ZXNvbWUK",
-		Object:  "chat.completion",
-		Created: int64(0),
-		Model:   "gpt-4o-mini",
-		Usage: usage{
-			PromptTokens:     input_tokens,
-			CompletionTokens: output_tokens,
-			TotalTokens:      input_tokens + output_tokens,
-		},
-		Choices: []Choice{
+// NewToolCallCompletion 返回包含工具调用的非流式响应，text 为工具调用之前的文本。
+func NewToolCallCompletion(meta ResponseMeta, text string, toolCalls []ToolCall, usage Usage) ChatCompletion {
+	completion := NewChatCompletion(meta, text, "tool_calls", usage)
+	if text == "" {
+		completion.Choices[0].Message.Content = nil
+	}
+	completion.Choices[0].Message.ToolCalls = toolCalls
+	return completion
+}
+
+// TextCompletion 是旧版文本补全接口的响应，流式输出时每个 chunk 也使用该结构。
+type TextCompletion struct {
+	ID                string       `json:"id"`
+	Object            string       `json:"object"`
+	Created           int64        `json:"created"`
+	Model             string       `json:"model"`
+	SystemFingerprint string       `json:"system_fingerprint"`
+	Choices           []TextChoice `json:"choices"`
+	Usage             *Usage       `json:"usage,omitempty"`
+}
+
+func (completion *TextCompletion) String() string {
+	resp, _ := json.Marshal(completion)
+	return string(resp)
+}
+
+type TextChoice struct {
+	Text         string `json:"text"`
+	Index        int    `json:"index"`
+	Logprobs     any    `json:"logprobs"`
+	FinishReason any    `json:"finish_reason"`
+}
+
+func NewTextCompletion(meta ResponseMeta, choices []TextChoice) TextCompletion {
+	return TextCompletion{
+		ID:                meta.ID,
+		Object:            "text_completion",
+		Created:           meta.Created,
+		Model:             meta.Model,
+		SystemFingerprint: SystemFingerprint,
+		Choices:           choices,
+		Usage:             &Usage{},
+	}
+}
+
+func NewTextCompletionChunk(meta ResponseMeta, index int, text string, finishReason any) TextCompletion {
+	return TextCompletion{
+		ID:                meta.ID,
+		Object:            "text_completion",
+		Created:           meta.Created,
+		Model:             meta.Model,
+		SystemFingerprint: SystemFingerprint,
+		Choices: []TextChoice{
 			{
-				Message: Msg{
-					Content: full_test,
-					Role:    "assistant",
-				},
-				Index: 0,
+				Text:         text,
+				Index:        index,
+				FinishReason: finishReason,
 			},
 		},
 	}
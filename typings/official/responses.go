@@ -0,0 +1,148 @@
+package official
+
+import "encoding/json"
+
+// ResponsesRequest 是 OpenAI Responses API (/v1/responses) 的请求体。
+type ResponsesRequest struct {
+	Model              string   `json:"model"`
+	Input              any      `json:"input"` // string 或输入项数组
+	Instructions       string   `json:"instructions,omitempty"`
+	PreviousResponseID string   `json:"previous_response_id,omitempty"`
+	Stream             bool     `json:"stream"`
+	Store              *bool    `json:"store,omitempty"`
+	MaxOutputTokens    int      `json:"max_output_tokens,omitempty"`
+	Temperature        *float64 `json:"temperature,omitempty"`
+	Metadata           any      `json:"metadata,omitempty"`
+
+	Reasoning *ResponsesReasoning `json:"reasoning,omitempty"`
+}
+
+type ResponsesReasoning struct {
+	Effort string `json:"effort,omitempty"`
+}
+
+// ReasoningEffort 返回 reasoning.effort，未指定时为空字符串。
+func (r *ResponsesRequest) ReasoningEffort() string {
+	if r.Reasoning == nil {
+		return ""
+	}
+	return r.Reasoning.Effort
+}
+
+// ShouldStore 返回是否需要在服务端保存本次响应，未指定时默认保存。
+func (r *ResponsesRequest) ShouldStore() bool {
+	return r.Store == nil || *r.Store
+}
+
+// ResponseInputItem 是 input 数组中的一项。
+// 省略 type 的 {role, content} 简写形式同样视为 message。
+type ResponseInputItem struct {
+	Type    string `json:"type,omitempty"`
+	Role    string `json:"role,omitempty"`
+	Content any    `json:"content,omitempty"` // string 或 []ResponseContentPart
+}
+
+type ResponseContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	Filename string `json:"filename,omitempty"`  // input_file
+	FileData string `json:"file_data,omitempty"` // input_file，data URL
+}
+
+// Response 是 Responses API 返回的响应对象。
+type Response struct {
+	ID                 string               `json:"id"`
+	Object             string               `json:"object"`
+	CreatedAt          int64                `json:"created_at"`
+	Status             string               `json:"status"`
+	Model              string               `json:"model"`
+	Output             []ResponseOutputItem `json:"output"`
+	Instructions       *string              `json:"instructions"`
+	PreviousResponseID *string              `json:"previous_response_id"`
+	IncompleteDetails  *IncompleteDetails   `json:"incomplete_details"`
+	Error              any                  `json:"error"`
+	Store              bool                 `json:"store"`
+	Metadata           any                  `json:"metadata"`
+	Usage              *ResponseUsage       `json:"usage"`
+}
+
+type ResponseOutputItem struct {
+	Type    string               `json:"type"`
+	ID      string               `json:"id"`
+	Status  string               `json:"status"`
+	Role    string               `json:"role"`
+	Content []ResponseOutputText `json:"content"`
+}
+
+type ResponseOutputText struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	Annotations []any  `json:"annotations"`
+}
+
+type IncompleteDetails struct {
+	Reason string `json:"reason"`
+}
+
+// ResponseError 是 status 为 failed 时的错误信息。
+type ResponseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ResponseUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+func NewResponse(id string, model string, createdAt int64) Response {
+	return Response{
+		ID:        id,
+		Object:    "response",
+		CreatedAt: createdAt,
+		Status:    "in_progress",
+		Model:     model,
+		Output:    []ResponseOutputItem{},
+	}
+}
+
+func NewResponseOutputMessage(id string, status string, text string) ResponseOutputItem {
+	item := ResponseOutputItem{
+		Type:    "message",
+		ID:      id,
+		Status:  status,
+		Role:    "assistant",
+		Content: []ResponseOutputText{},
+	}
+	if status == "completed" || status == "incomplete" {
+		item.Content = append(item.Content, NewResponseOutputText(text))
+	}
+	return item
+}
+
+func NewResponseOutputText(text string) ResponseOutputText {
+	return ResponseOutputText{Type: "output_text", Text: text, Annotations: []any{}}
+}
+
+// ResponseStreamEvent 是 Responses API 流式输出中的一个类型化事件。
+type ResponseStreamEvent struct {
+	Type           string              `json:"type"`
+	SequenceNumber int                 `json:"sequence_number"`
+	Response       *Response           `json:"response,omitempty"`
+	OutputIndex    *int                `json:"output_index,omitempty"`
+	ContentIndex   *int                `json:"content_index,omitempty"`
+	ItemID         string              `json:"item_id,omitempty"`
+	Item           *ResponseOutputItem `json:"item,omitempty"`
+	Part           *ResponseOutputText `json:"part,omitempty"`
+	Delta          *string             `json:"delta,omitempty"`
+	Text           *string             `json:"text,omitempty"`
+	Code           string              `json:"code,omitempty"`    // 仅 error 事件
+	Message        string              `json:"message,omitempty"` // 仅 error 事件
+}
+
+func (e *ResponseStreamEvent) String() string {
+	resp, _ := json.Marshal(e)
+	return string(resp)
+}
@@ -0,0 +1,38 @@
+package ollama
+
+// ChatRequest 是 Ollama /api/chat 的请求体。
+// Ollama 默认以流式输出，因此 Stream 使用指针区分未设置和 false。
+type ChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   *bool     `json:"stream,omitempty"`
+	Options  *Options  `json:"options,omitempty"`
+}
+
+// GenerateRequest 是 Ollama /api/generate 的请求体。
+type GenerateRequest struct {
+	Model   string   `json:"model"`
+	Prompt  string   `json:"prompt"`
+	System  string   `json:"system,omitempty"`
+	Images  []string `json:"images,omitempty"`
+	Stream  *bool    `json:"stream,omitempty"`
+	Options *Options `json:"options,omitempty"`
+}
+
+// Message 中的 Images 为不带 data URL 前缀的 base64 图片。
+type Message struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+type Options struct {
+	Stop        []string `json:"stop,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+// IsStream 返回是否流式输出，未设置时默认为 true。
+func IsStream(stream *bool) bool {
+	return stream == nil || *stream
+}
@@ -0,0 +1,82 @@
+package ollama
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ChatResponse 是 /api/chat 的响应，流式输出时每行 JSON 也使用该结构。
+type ChatResponse struct {
+	Model     string  `json:"model"`
+	CreatedAt string  `json:"created_at"`
+	Message   Message `json:"message"`
+	Done      bool    `json:"done"`
+	Stats
+}
+
+// GenerateResponse 是 /api/generate 的响应，流式输出时每行 JSON 也使用该结构。
+type GenerateResponse struct {
+	Model     string `json:"model"`
+	CreatedAt string `json:"created_at"`
+	Response  string `json:"response"`
+	Done      bool   `json:"done"`
+	Stats
+}
+
+// Stats 是最后一条响应中附带的结束原因和统计信息，时间单位为纳秒。
+type Stats struct {
+	DoneReason         string `json:"done_reason,omitempty"`
+	TotalDuration      int64  `json:"total_duration,omitempty"`
+	LoadDuration       int64  `json:"load_duration,omitempty"`
+	PromptEvalCount    int    `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64  `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int    `json:"eval_count,omitempty"`
+	EvalDuration       int64  `json:"eval_duration,omitempty"`
+}
+
+func NewChatResponse(model string, content string, done bool) ChatResponse {
+	return ChatResponse{
+		Model:     model,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Message:   Message{Role: "assistant", Content: content},
+		Done:      done,
+	}
+}
+
+func NewGenerateResponse(model string, response string, done bool) GenerateResponse {
+	return GenerateResponse{
+		Model:     model,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Response:  response,
+		Done:      done,
+	}
+}
+
+// TagsResponse 是 /api/tags 的响应。
+type TagsResponse struct {
+	Models []ModelInfo `json:"models"`
+}
+
+type ModelInfo struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt string       `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+type ModelDetails struct {
+	ParentModel       string   `json:"parent_model"`
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// Line 将响应序列化为一行 NDJSON。
+func Line(v any) string {
+	resp, _ := json.Marshal(v)
+	return string(resp) + "\n"
+}
@@ -3,7 +3,9 @@ package util
 import (
 	"log/slog"
 	"math/rand"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/pkoukk/tiktoken-go"
 )
@@ -28,13 +30,88 @@ func RandomHexadecimalString() string {
 	}
 	return string(b)
 }
-func CountToken(input string) int {
-	encoding := "gpt-4o-mini"
-	tkm, err := tiktoken.EncodingForModel(encoding)
+
+// tokenizerRetryInterval 是分词器加载失败后再次尝试的间隔。
+// tiktoken 首次使用时需要联网下载编码表，网络不可用时会回退到估算值。
+const tokenizerRetryInterval = time.Minute
+
+var (
+	tokenizerMu      sync.Mutex
+	tokenizer        *tiktoken.Tiktoken
+	tokenizerRetryAt time.Time
+)
+
+func getTokenizer() *tiktoken.Tiktoken {
+	tokenizerMu.Lock()
+	defer tokenizerMu.Unlock()
+
+	if tokenizer != nil || time.Now().Before(tokenizerRetryAt) {
+		return tokenizer
+	}
+	tkm, err := tiktoken.EncodingForModel("gpt-4o-mini")
 	if err != nil {
-		slog.Warn("tiktoken.EncodingForModel error", "err", err)
+		slog.Warn("tiktoken.EncodingForModel error, falling back to estimation", "err", err)
+		tokenizerRetryAt = time.Now().Add(tokenizerRetryInterval)
+		return nil
+	}
+	tokenizer = tkm
+	return tokenizer
+}
+
+func CountToken(input string) int {
+	if input == "" {
 		return 0
 	}
+	tkm := getTokenizer()
+	if tkm == nil {
+		return EstimateToken(input)
+	}
 	token := tkm.Encode(input, nil, nil)
 	return len(token)
 }
+
+// EstimateToken 在分词器不可用时粗略估算 token 数：
+// ASCII 字符约 4 个一个 token，其余字符（如中日韩文字）各计一个 token。
+func EstimateToken(input string) int {
+	ascii, other := 0, 0
+	for _, r := range input {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// TruncateToTokens 截取 input 开头不超过 maxTokens 个 token 的部分。
+// 分词器不可用时按 EstimateToken 的规则逐字符估算。
+func TruncateToTokens(input string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	if tkm := getTokenizer(); tkm != nil {
+		tokens := tkm.Encode(input, nil, nil)
+		if len(tokens) <= maxTokens {
+			return input
+		}
+		truncated := tkm.Decode(tokens[:maxTokens])
+		// 截断位置可能落在多字节字符中间，去掉不完整的字符
+		for len(truncated) > 0 && !utf8.ValidString(truncated) {
+			truncated = truncated[:len(truncated)-1]
+		}
+		return truncated
+	}
+	ascii, other := 0, 0
+	for i, r := range input {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+		if (ascii+3)/4+other > maxTokens {
+			return input[:i]
+		}
+	}
+	return input
+}
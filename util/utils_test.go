@@ -9,3 +9,15 @@ func TestRandomHexadecimalString(t *testing.T) {
 	var str = RandomHexadecimalString()
 	fmt.Println(str)
 }
+
+func TestEstimateToken(t *testing.T) {
+	if got := EstimateToken("hello world!"); got != 3 {
+		t.Fatalf("EstimateToken ascii = %d, want 3", got)
+	}
+	if got := EstimateToken("你好"); got != 2 {
+		t.Fatalf("EstimateToken cjk = %d, want 2", got)
+	}
+	if got := CountToken(""); got != 0 {
+		t.Fatalf("CountToken empty = %d, want 0", got)
+	}
+}